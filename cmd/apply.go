@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+
+	"talos-proxmox-deployer/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+var applyNodes []string
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "重新应用节点配置",
+	Long:  `根据 cluster-config.yaml 重新生成 <集群>-config/nodes/<节点>.yaml 并应用到已运行的节点`,
+	RunE:  runApply,
+}
+
+func init() {
+	applyCmd.Flags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
+	applyCmd.Flags().StringSliceVarP(&applyNodes, "node", "n", nil, "只应用到指定节点（可重复）")
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("配置验证失败: %w", err)
+	}
+
+	d, err := newDeployer(cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("📝 生成节点配置...")
+	if err := d.RenderNodeConfigs(); err != nil {
+		return err
+	}
+	return d.ReapplyConfig(applyNodes)
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"talos-proxmox-deployer/pkg/config"
 	"talos-proxmox-deployer/pkg/deployer"
@@ -15,6 +16,9 @@ var (
 	skipTemplate  bool
 	skipConfig    bool
 	skipBootstrap bool
+	resumeDeploy  bool
+	fromStep      string
+	parallelNodes int
 )
 
 var deployCmd = &cobra.Command{
@@ -30,6 +34,9 @@ func init() {
 	deployCmd.Flags().BoolVarP(&skipTemplate, "skip-template", "t", false, "跳过模板创建")
 	deployCmd.Flags().BoolVar(&skipConfig, "skip-config", false, "跳过配置生成")
 	deployCmd.Flags().BoolVar(&skipBootstrap, "skip-bootstrap", false, "跳过集群引导")
+	deployCmd.Flags().BoolVar(&resumeDeploy, "resume", false, "从上次失败的步骤和节点继续")
+	deployCmd.Flags().StringVar(&fromStep, "from-step", "", "从指定步骤开始: "+strings.Join(deployer.Steps, ", "))
+	deployCmd.Flags().IntVar(&parallelNodes, "parallel", 0, "同时创建和配置的节点数（默认使用配置文件中的 deploy.parallel）")
 }
 
 func runDeploy(cmd *cobra.Command, args []string) error {
@@ -50,44 +57,38 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("集群名称: %s\n", cfg.ClusterName)
 	fmt.Printf("Talos 版本: %s\n", cfg.TalosVersion)
+	if cfg.KubernetesVersion != "" {
+		fmt.Printf("Kubernetes 版本: %s\n", cfg.KubernetesVersionFull())
+	}
 	fmt.Printf("控制平面节点: %d\n", len(cfg.Nodes.ControlPlanes))
 	fmt.Printf("工作节点: %d\n", len(cfg.Nodes.Workers))
 	fmt.Println()
 
-	// 创建部署器
-	d := deployer.New(cfg)
-
-	// 执行部署步骤
-	if !skipPrepare {
-		if err := d.PrepareImage(); err != nil {
-			return fmt.Errorf("准备镜像失败: %w", err)
-		}
+	if parallelNodes < 0 {
+		return fmt.Errorf("--parallel 不能为负数: %d", parallelNodes)
 	}
 
-	if !skipTemplate {
-		if err := d.CreateTemplate(); err != nil {
-			return fmt.Errorf("创建模板失败: %w", err)
-		}
-	}
-
-	if err := d.CreateNodes(); err != nil {
-		return fmt.Errorf("创建节点失败: %w", err)
-	}
-
-	if !skipConfig {
-		if err := d.GenerateConfig(); err != nil {
-			return fmt.Errorf("生成配置失败: %w", err)
-		}
-	}
-
-	if err := d.ApplyConfig(); err != nil {
-		return fmt.Errorf("应用配置失败: %w", err)
+	// 创建部署器
+	d, err := newDeployer(cfg)
+	if err != nil {
+		return err
 	}
 
-	if !skipBootstrap {
-		if err := d.Bootstrap(); err != nil {
-			return fmt.Errorf("引导集群失败: %w", err)
-		}
+	// 执行部署步骤
+	if err := d.Deploy(deployer.DeployOptions{
+		Skip: map[string]bool{
+			deployer.StepPrepareImage:   skipPrepare,
+			deployer.StepCreateTemplate: skipTemplate,
+			deployer.StepGenerateConfig: skipConfig,
+			deployer.StepBootstrap:      skipBootstrap,
+		},
+		Resume:   resumeDeploy,
+		FromStep: fromStep,
+		Parallel: parallelNodes,
+	}); err != nil {
+		fmt.Println()
+		fmt.Println("❌ 部署失败，修复问题后可以使用 --resume 从失败处继续")
+		return err
 	}
 
 	fmt.Println()
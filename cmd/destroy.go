@@ -1,10 +1,7 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
-	"os"
-	"strings"
 
 	"talos-proxmox-deployer/pkg/config"
 	"talos-proxmox-deployer/pkg/deployer"
@@ -12,18 +9,35 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var forceDestroy bool
+var (
+	forceDestroy  bool
+	destroyDryRun bool
+	destroyOpts   deployer.DestroyOptions
+)
 
 var destroyCmd = &cobra.Command{
 	Use:   "destroy",
 	Short: "销毁集群",
-	Long:  `停止并删除所有集群节点和配置`,
-	RunE:  runDestroy,
+	Long: `停止并删除所有集群节点和配置。
+删除前检查每个虚拟机的名称、集群标签和部署状态，确认属于该集群后才会删除，
+归属不匹配时拒绝执行；模板仍被其他集群的节点使用时保留模板。
+
+使用 --node 或 --workers 只销毁部分工作节点，此时模板、镜像和配置都会保留，
+节点仍保留在配置文件中，之后可通过 deploy --from-step create-nodes 重新创建。`,
+	RunE: runDestroy,
 }
 
 func init() {
 	destroyCmd.Flags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
 	destroyCmd.Flags().BoolVarP(&forceDestroy, "force", "f", false, "强制销毁，不询问确认")
+	destroyCmd.Flags().BoolVar(&destroyDryRun, "dry-run", false, "只输出销毁计划，不执行")
+	destroyCmd.Flags().BoolVar(&destroyOpts.IgnoreOwnership, "ignore-ownership", false, "删除归属检查不通过的虚拟机（危险）")
+	destroyCmd.Flags().BoolVar(&destroyOpts.KeepTemplate, "keep-template", false, "保留模板")
+	destroyCmd.Flags().BoolVar(&destroyOpts.KeepImage, "keep-image", false, "保留下载的 Talos 镜像")
+	destroyCmd.Flags().BoolVar(&destroyOpts.KeepConfig, "keep-config", false, "保留配置目录（talosconfig、kubeconfig 和 secrets）")
+	destroyCmd.Flags().StringVar(&destroyOpts.Archive, "archive", "", "删除前将配置目录打包为带时间戳的 tar.gz 保存到该目录")
+	destroyCmd.Flags().StringSliceVarP(&destroyOpts.Nodes, "node", "n", nil, "只销毁指定的工作节点（可重复）")
+	destroyCmd.Flags().BoolVar(&destroyOpts.WorkersOnly, "workers", false, "只销毁工作节点")
 }
 
 func runDestroy(cmd *cobra.Command, args []string) error {
@@ -31,27 +45,43 @@ func runDestroy(cmd *cobra.Command, args []string) error {
 	fmt.Println("============")
 	fmt.Println()
 
-	if !forceDestroy {
-		fmt.Print("确定要销毁整个集群吗？这不可恢复！(yes/no): ")
-		reader := bufio.NewReader(os.Stdin)
-		answer, _ := reader.ReadString('\n')
-		answer = strings.TrimSpace(strings.ToLower(answer))
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	d, err := newDeployer(cfg)
+	if err != nil {
+		return err
+	}
+	plan, err := d.PlanDestroy(destroyOpts)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
 
-		if answer != "yes" {
+	if destroyDryRun {
+		fmt.Println("--dry-run，未执行")
+		return nil
+	}
+	partial := len(destroyOpts.Nodes) > 0 || destroyOpts.WorkersOnly
+	prompt := "确定要销毁整个集群吗？这不可恢复！(yes/no): "
+	if partial {
+		prompt = "确定要销毁以上节点吗？这不可恢复！(yes/no): "
+	}
+	if !forceDestroy {
+		if !confirm(prompt) {
 			fmt.Println("操作已取消")
 			return nil
 		}
 	}
 
-	cfg, err := config.Load(configFile)
-	if err != nil {
-		return fmt.Errorf("加载配置失败: %w", err)
-	}
-
-	d := deployer.New(cfg)
-	if err := d.Destroy(); err != nil {
+	if err := d.Destroy(plan); err != nil {
 		return fmt.Errorf("销毁集群失败: %w", err)
 	}
+	if partial {
+		return nil
+	}
 
 	fmt.Println()
 	fmt.Println("✅ 集群已销毁")
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"talos-proxmox-deployer/pkg/deployer"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	etcdBackupDir     string
+	etcdWipeEphemeral bool
+	etcdSkipHashCheck bool
+	forceRestore      bool
+)
+
+var etcdCmd = &cobra.Command{
+	Use:   "etcd",
+	Short: "备份和恢复 etcd",
+	Long:  `备份集群 etcd 数据，并在灾难时从备份恢复`,
+}
+
+var etcdBackupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "备份 etcd",
+	Long: `通过第一个 etcd 健康的控制平面执行 talosctl etcd snapshot，
+备份以时间戳命名并附带 SHA-256 校验文件，超过保留数量的旧备份会被删除。`,
+	RunE: runEtcdBackup,
+}
+
+var etcdListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出 etcd 备份",
+	RunE:  runEtcdList,
+}
+
+var etcdRestoreCmd = &cobra.Command{
+	Use:   "restore <snapshot>",
+	Short: "从备份恢复 etcd",
+	Long: `Talos 灾难恢复流程：校验快照后，在第一个控制平面上执行 talosctl bootstrap --recover-from，
+其余控制平面随后自动加入。恢复要求所有控制平面上的 etcd 都未运行（例如重建后的控制平面），
+etcd 仍在运行时可使用 --wipe-ephemeral 先清除所有控制平面的 EPHEMERAL 分区。`,
+	Args: cobra.ExactArgs(1),
+	RunE: runEtcdRestore,
+}
+
+func init() {
+	etcdCmd.AddCommand(etcdBackupCmd)
+	etcdCmd.AddCommand(etcdListCmd)
+	etcdCmd.AddCommand(etcdRestoreCmd)
+
+	etcdCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
+	etcdBackupCmd.Flags().StringVarP(&etcdBackupDir, "dir", "d", "", "备份目录（默认使用配置中的 etcd.backup_dir）")
+	etcdListCmd.Flags().StringVarP(&etcdBackupDir, "dir", "d", "", "备份目录（默认使用配置中的 etcd.backup_dir）")
+	etcdRestoreCmd.Flags().BoolVar(&etcdWipeEphemeral, "wipe-ephemeral", false, "etcd 仍在运行时，先清除所有控制平面的 EPHEMERAL 分区")
+	etcdRestoreCmd.Flags().BoolVar(&etcdSkipHashCheck, "skip-hash-check", false, "跳过快照完整性校验（快照直接复制自 etcd 数据目录时使用）")
+	etcdRestoreCmd.Flags().BoolVarP(&forceRestore, "force", "f", false, "强制恢复，不询问确认")
+}
+
+func runEtcdBackup(cmd *cobra.Command, args []string) error {
+	d, err := loadDeployer()
+	if err != nil {
+		return err
+	}
+	_, err = d.BackupEtcd(etcdBackupDir)
+	return err
+}
+
+func runEtcdList(cmd *cobra.Command, args []string) error {
+	d, err := loadDeployer()
+	if err != nil {
+		return err
+	}
+
+	backups, err := d.ListEtcdBackups(etcdBackupDir)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		fmt.Println("没有 etcd 备份")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "文件\t时间\t大小\tSHA-256")
+	for _, b := range backups {
+		sum := b.SHA256
+		if len(sum) > 12 {
+			sum = sum[:12]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.1f MiB\t%s\n",
+			b.Path, b.Time.Format("2006-01-02 15:04:05"), float64(b.Size)/(1<<20), sum)
+	}
+	w.Flush()
+	return nil
+}
+
+func runEtcdRestore(cmd *cobra.Command, args []string) error {
+	if !forceRestore {
+		if !confirm(fmt.Sprintf("确定要从 %s 恢复 etcd 吗？快照之后的集群状态变更都会丢失！(yes/no): ", args[0])) {
+			fmt.Println("操作已取消")
+			return nil
+		}
+	}
+
+	d, err := loadDeployer()
+	if err != nil {
+		return err
+	}
+	return d.RestoreEtcd(deployer.EtcdRestoreOptions{
+		Snapshot:      args[0],
+		WipeEphemeral: etcdWipeEphemeral,
+		SkipHashCheck: etcdSkipHashCheck,
+	})
+}
@@ -8,38 +8,77 @@ import (
 	"talos-proxmox-deployer/pkg/deployer"
 )
 
+var (
+	manageNodes     []string
+	manageRole      string
+	manageSelector  map[string]string
+	maintenanceDone bool
+)
+
 var manageCmd = &cobra.Command{
 	Use:   "manage",
 	Short: "管理集群",
-	Long:  `启动、停止、重启集群节点`,
+	Long: `启动、停止、重启集群节点，或让单个节点进入维护。
+start、stop 和 restart 默认作用于所有节点，可通过 --node、--role 和 --selector 选择部分节点，
+多个条件需同时满足。`,
 }
 
 var startCmd = &cobra.Command{
 	Use:   "start",
 	Short: "启动集群节点",
+	Long:  `先启动控制平面并等待 etcd 恢复 quorum，再启动工作节点`,
 	RunE:  runStart,
 }
 
 var stopCmd = &cobra.Command{
 	Use:   "stop",
 	Short: "停止集群节点",
-	RunE:  runStop,
+	Long: `先关闭工作节点，最后关闭控制平面。每个节点先通过 Talos 优雅关机，
+Talos 不可用或超时后通过 ACPI 关机，ACPI 再超时后才强制停止（超时见 deploy.timeouts.shutdown）。`,
+	RunE: runStop,
 }
 
 var restartCmd = &cobra.Command{
 	Use:   "restart",
-	Short: "重启集群节点",
-	RunE:  runRestart,
+	Short: "滚动重启集群节点",
+	Long: `控制平面在前，逐个重启节点。每个节点重启后等待其 etcd 健康并在 Kubernetes 中重新 Ready，
+再重启下一个，任一节点失败立即停止。`,
+	RunE: runRestart,
+}
+
+var maintenanceCmd = &cobra.Command{
+	Use:   "maintenance <node>",
+	Short: "节点维护",
+	Long: `cordon 并 drain 节点后优雅关机，之后可以维护节点所在的 Proxmox 主机。
+维护完成后使用 --end 启动节点，等待其在 Kubernetes 中重新 Ready 后恢复调度。
+控制平面关机会破坏 etcd quorum 时拒绝执行。`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMaintenance,
 }
 
 func init() {
 	manageCmd.AddCommand(startCmd)
 	manageCmd.AddCommand(stopCmd)
 	manageCmd.AddCommand(restartCmd)
+	manageCmd.AddCommand(maintenanceCmd)
 
-	startCmd.Flags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
-	stopCmd.Flags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
-	restartCmd.Flags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
+	for _, cmd := range []*cobra.Command{startCmd, stopCmd, restartCmd} {
+		cmd.Flags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
+		cmd.Flags().StringSliceVarP(&manageNodes, "node", "n", nil, "只操作指定节点（可重复）")
+		cmd.Flags().StringVar(&manageRole, "role", "", "只操作指定角色的节点: controlplane 或 worker")
+		cmd.Flags().StringToStringVarP(&manageSelector, "selector", "l", nil, "只操作带有指定标签的节点 key=value（可重复）")
+	}
+	maintenanceCmd.Flags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
+	maintenanceCmd.Flags().BoolVar(&maintenanceDone, "end", false, "结束维护：启动节点并恢复调度")
+}
+
+// nodeSelector 根据命令行参数构造节点选择条件
+func nodeSelector() deployer.NodeSelector {
+	return deployer.NodeSelector{
+		Names:  manageNodes,
+		Role:   manageRole,
+		Labels: manageSelector,
+	}
 }
 
 func runStart(cmd *cobra.Command, args []string) error {
@@ -48,8 +87,11 @@ func runStart(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	d := deployer.New(cfg)
-	return d.StartNodes()
+	d, err := newDeployer(cfg)
+	if err != nil {
+		return err
+	}
+	return d.StartNodes(nodeSelector())
 }
 
 func runStop(cmd *cobra.Command, args []string) error {
@@ -58,8 +100,11 @@ func runStop(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	d := deployer.New(cfg)
-	return d.StopNodes()
+	d, err := newDeployer(cfg)
+	if err != nil {
+		return err
+	}
+	return d.StopNodes(nodeSelector())
 }
 
 func runRestart(cmd *cobra.Command, args []string) error {
@@ -68,9 +113,24 @@ func runRestart(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	d := deployer.New(cfg)
-	if err := d.StopNodes(); err != nil {
+	d, err := newDeployer(cfg)
+	if err != nil {
+		return err
+	}
+	return d.RestartNodes(nodeSelector())
+}
+
+func runMaintenance(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return err
+	}
+	d, err := newDeployer(cfg)
+	if err != nil {
 		return err
 	}
-	return d.StartNodes()
+	if maintenanceDone {
+		return d.ExitMaintenance(args[0])
+	}
+	return d.EnterMaintenance(args[0])
 }
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	nodeAddName   string
+	nodeAddVMID   int
+	nodeAddIP     string
+	nodeAddCPU    int
+	nodeAddMemory int
+	nodeAddDisk   string
+	nodeAddLabels map[string]string
+	forceRemove   bool
+)
+
+var nodeCmd = &cobra.Command{
+	Use:   "node",
+	Short: "添加、移除或替换节点",
+	Long:  `向运行中的集群添加或移除工作节点（同步更新 cluster-config.yaml），或重建故障节点`,
+}
+
+var nodeAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "添加工作节点",
+	Long: `将新工作节点写入配置文件，只创建该节点的虚拟机，基于已有的 worker.yaml 生成并应用节点配置，
+然后等待节点在 Kubernetes 中 Ready。未指定的名称、VM ID、IP 和资源按最后一个工作节点的规律自动分配。`,
+	RunE: runNodeAdd,
+}
+
+var nodeRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "移除工作节点",
+	Long:  `cordon 并 drain 节点，通过 Talos 重置节点，删除 Kubernetes 节点对象并销毁虚拟机，最后从配置文件中删除该节点。`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runNodeRemove,
+}
+
+var nodeReplaceCmd = &cobra.Command{
+	Use:   "replace <name>",
+	Short: "替换故障节点",
+	Long: `销毁节点虚拟机并从模板重新克隆，重新应用该节点的配置，等待其重新加入集群。
+控制平面节点会先通过健康的控制平面从 etcd 中移除旧成员，并等待新节点重新加入 etcd；
+移除会导致 etcd 失去 quorum 时拒绝执行。`,
+	Args: cobra.ExactArgs(1),
+	RunE: runNodeReplace,
+}
+
+func init() {
+	nodeCmd.AddCommand(nodeAddCmd)
+	nodeCmd.AddCommand(nodeRemoveCmd)
+	nodeCmd.AddCommand(nodeReplaceCmd)
+
+	nodeCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
+	nodeAddCmd.Flags().StringVar(&nodeAddName, "name", "", "节点名（默认按现有工作节点顺延）")
+	nodeAddCmd.Flags().IntVar(&nodeAddVMID, "vm-id", 0, "VM ID（默认按现有工作节点顺延）")
+	nodeAddCmd.Flags().StringVar(&nodeAddIP, "ip", "", "IP 地址（默认按现有工作节点顺延）")
+	nodeAddCmd.Flags().IntVar(&nodeAddCPU, "cpu", 0, "CPU 核数（默认与最后一个工作节点相同）")
+	nodeAddCmd.Flags().IntVar(&nodeAddMemory, "memory", 0, "内存 MB（默认与最后一个工作节点相同）")
+	nodeAddCmd.Flags().StringVar(&nodeAddDisk, "disk", "", "磁盘大小，如 50G（默认与最后一个工作节点相同）")
+	nodeAddCmd.Flags().StringToStringVar(&nodeAddLabels, "label", nil, "Kubernetes 节点标签 key=value（可重复）")
+	nodeRemoveCmd.Flags().BoolVarP(&forceRemove, "force", "f", false, "强制移除，不询问确认")
+	nodeReplaceCmd.Flags().BoolVarP(&forceRemove, "force", "f", false, "强制替换，不询问确认")
+}
+
+func runNodeAdd(cmd *cobra.Command, args []string) error {
+	d, err := loadDeployer()
+	if err != nil {
+		return err
+	}
+
+	nodes, err := d.NewWorkers(1)
+	if err != nil {
+		return err
+	}
+	node := nodes[0]
+	if nodeAddName != "" {
+		node.Name = nodeAddName
+	}
+	if nodeAddVMID != 0 {
+		node.VMID = nodeAddVMID
+	}
+	if nodeAddIP != "" {
+		node.IPAddress = nodeAddIP
+	}
+	if nodeAddCPU != 0 {
+		node.CPU = nodeAddCPU
+	}
+	if nodeAddMemory != 0 {
+		node.Memory = nodeAddMemory
+	}
+	if nodeAddDisk != "" {
+		node.Disk = nodeAddDisk
+	}
+	if len(nodeAddLabels) > 0 {
+		node.Labels = nodeAddLabels
+	}
+
+	nodes[0] = node
+	return d.AddWorkers(nodes)
+}
+
+func runNodeRemove(cmd *cobra.Command, args []string) error {
+	if !forceRemove {
+		if !confirm(fmt.Sprintf("确定要移除节点 %s 并销毁其虚拟机吗？(yes/no): ", args[0])) {
+			fmt.Println("操作已取消")
+			return nil
+		}
+	}
+
+	d, err := loadDeployer()
+	if err != nil {
+		return err
+	}
+	return d.RemoveNode(args[0])
+}
+
+func runNodeReplace(cmd *cobra.Command, args []string) error {
+	if !forceRemove {
+		if !confirm(fmt.Sprintf("确定要销毁并重建节点 %s 吗？节点上的本地数据会丢失！(yes/no): ", args[0])) {
+			fmt.Println("操作已取消")
+			return nil
+		}
+	}
+
+	d, err := loadDeployer()
+	if err != nil {
+		return err
+	}
+	return d.ReplaceNode(args[0])
+}
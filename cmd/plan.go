@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"talos-proxmox-deployer/pkg/config"
+	"talos-proxmox-deployer/pkg/deployer"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	planOutput           string
+	planDetailedExitCode bool
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "预览 deploy 将执行的变更",
+	Long: `对比 cluster-config.yaml 与 Proxmox 和本地配置目录的实际状态，列出 deploy 将创建、更新和删除的资源。
+
+使用 --detailed-exitcode 时，退出码 0 表示无变更，1 表示出错，2 表示有待执行的变更。`,
+	RunE: runPlan,
+}
+
+func init() {
+	planCmd.Flags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
+	planCmd.Flags().StringVarP(&planOutput, "output", "o", "text", "输出格式: text 或 json")
+	planCmd.Flags().BoolVar(&planDetailedExitCode, "detailed-exitcode", false, "有待执行的变更时以退出码 2 退出")
+}
+
+func runPlan(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("配置验证失败: %w", err)
+	}
+
+	d, err := newDeployer(cfg)
+	if err != nil {
+		return err
+	}
+
+	plan, err := d.Plan()
+	if err != nil {
+		return fmt.Errorf("计算变更失败: %w", err)
+	}
+
+	switch planOutput {
+	case "json":
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "text":
+		printPlan(plan)
+	default:
+		return fmt.Errorf("无效的输出格式: %s，必须是 'text' 或 'json'", planOutput)
+	}
+
+	if plan.HasConflicts() {
+		return fmt.Errorf("存在无法自动处理的冲突，请先修正配置")
+	}
+	if planDetailedExitCode && plan.HasChanges() {
+		os.Exit(2)
+	}
+	return nil
+}
+
+// planSymbols 各操作类型的显示符号
+var planSymbols = map[deployer.PlanAction]string{
+	deployer.ActionCreate:   "+",
+	deployer.ActionUpdate:   "~",
+	deployer.ActionDelete:   "-",
+	deployer.ActionNoop:     " ",
+	deployer.ActionConflict: "!",
+}
+
+func printPlan(plan *deployer.Plan) {
+	fmt.Printf("📋 集群 %s 的变更计划\n", plan.Cluster)
+	fmt.Println("========================")
+	fmt.Println()
+
+	for _, item := range plan.Items {
+		line := fmt.Sprintf("  %s %-8s %s", planSymbols[item.Action], item.Action, item.Resource)
+		if item.VMID != 0 {
+			line += fmt.Sprintf(" (VM ID: %d)", item.VMID)
+		}
+		if item.Reason != "" {
+			line += " - " + item.Reason
+		}
+		fmt.Println(line)
+		for _, c := range item.Changes {
+			fmt.Printf("        %s\n", c)
+		}
+	}
+
+	summary := plan.Summary()
+	fmt.Println()
+	fmt.Printf("计划: %d 创建, %d 更新, %d 删除, %d 不变",
+		summary[deployer.ActionCreate], summary[deployer.ActionUpdate],
+		summary[deployer.ActionDelete], summary[deployer.ActionNoop])
+	if n := summary[deployer.ActionConflict]; n > 0 {
+		fmt.Printf(", %d 冲突", n)
+	}
+	fmt.Println()
+}
@@ -1,6 +1,14 @@
 package cmd
 
 import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"talos-proxmox-deployer/pkg/config"
+	"talos-proxmox-deployer/pkg/deployer"
+
 	"github.com/spf13/cobra"
 )
 
@@ -16,8 +24,47 @@ func Execute() error {
 
 func init() {
 	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(planCmd)
 	rootCmd.AddCommand(deployCmd)
 	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(destroyCmd)
 	rootCmd.AddCommand(manageCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(secretsCmd)
+	rootCmd.AddCommand(stateCmd)
+	rootCmd.AddCommand(upgradeCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(etcdCmd)
+	rootCmd.AddCommand(nodeCmd)
+	rootCmd.AddCommand(scaleCmd)
+}
+
+// newDeployer 根据配置创建部署器及其 Proxmox 后端
+func newDeployer(cfg *config.ClusterConfig) (*deployer.Deployer, error) {
+	backend, err := deployer.NewBackend(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("初始化 Proxmox 后端失败: %w", err)
+	}
+	return deployer.New(cfg, backend), nil
+}
+
+// loadDeployer 加载并验证配置，创建部署器
+func loadDeployer() (*deployer.Deployer, error) {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("加载配置失败: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("配置验证失败: %w", err)
+	}
+	return newDeployer(cfg)
+}
+
+// confirm 打印提示并读取确认，只有输入 yes 时返回 true
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	reader := bufio.NewReader(os.Stdin)
+	answer, _ := reader.ReadString('\n')
+	return strings.TrimSpace(strings.ToLower(answer)) == "yes"
 }
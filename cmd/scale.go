@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	scaleCount int
+	forceScale bool
+)
+
+var scaleCmd = &cobra.Command{
+	Use:   "scale",
+	Short: "调整节点数量",
+}
+
+var scaleWorkersCmd = &cobra.Command{
+	Use:   "workers",
+	Short: "调整工作节点数量",
+	Long: `将工作节点数量调整为 --count 并同步更新 cluster-config.yaml。
+扩容时按最后一个工作节点的命名、VM ID、IP 和资源规律生成新节点，只创建新节点；
+缩容时从最后一个工作节点开始，逐个 drain、重置并销毁。`,
+	RunE: runScaleWorkers,
+}
+
+func init() {
+	scaleCmd.AddCommand(scaleWorkersCmd)
+
+	scaleCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
+	scaleWorkersCmd.Flags().IntVar(&scaleCount, "count", -1, "目标工作节点数量")
+	scaleWorkersCmd.Flags().BoolVarP(&forceScale, "force", "f", false, "缩容时不询问确认")
+	scaleWorkersCmd.MarkFlagRequired("count")
+}
+
+func runScaleWorkers(cmd *cobra.Command, args []string) error {
+	if scaleCount < 0 {
+		return fmt.Errorf("--count 不能为负数: %d", scaleCount)
+	}
+
+	d, err := loadDeployer()
+	if err != nil {
+		return err
+	}
+
+	if current := d.WorkerCount(); scaleCount < current && !forceScale {
+		if !confirm(fmt.Sprintf("将移除 %d 个工作节点并销毁其虚拟机，确定继续吗？(yes/no): ", current-scaleCount)) {
+			fmt.Println("操作已取消")
+			return nil
+		}
+	}
+	return d.ScaleWorkers(scaleCount)
+}
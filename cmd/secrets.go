@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+
+	"talos-proxmox-deployer/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	secretsOutput string
+	secretsForce  bool
+)
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "管理 Talos secrets bundle",
+	Long:  `导出或导入集群的 Talos secrets bundle（CA、token 等），用于备份和在其他机器上重新生成配置`,
+}
+
+var secretsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "导出 secrets bundle",
+	RunE:  runSecretsExport,
+}
+
+var secretsImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "从备份导入 secrets bundle",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSecretsImport,
+}
+
+func init() {
+	secretsCmd.AddCommand(secretsExportCmd)
+	secretsCmd.AddCommand(secretsImportCmd)
+
+	secretsCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
+	secretsExportCmd.Flags().StringVarP(&secretsOutput, "output", "o", "", "导出文件路径（默认: <集群名>-secrets.yaml）")
+	secretsImportCmd.Flags().BoolVarP(&secretsForce, "force", "f", false, "覆盖已有的 secrets")
+}
+
+func runSecretsExport(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	d, err := newDeployer(cfg)
+	if err != nil {
+		return err
+	}
+
+	output := secretsOutput
+	if output == "" {
+		output = fmt.Sprintf("%s-secrets.yaml", cfg.ClusterName)
+	}
+	if err := d.ExportSecrets(output); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ secrets 已导出到: %s\n", output)
+	fmt.Println("⚠️  该文件包含集群 CA 私钥，请妥善保管")
+	return nil
+}
+
+func runSecretsImport(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	d, err := newDeployer(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := d.ImportSecrets(args[0], secretsForce); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ secrets 已导入到: %s\n", d.SecretsPath())
+	fmt.Println("下一步: talos-deployer deploy --skip-prepare --skip-template 重新生成配置")
+	return nil
+}
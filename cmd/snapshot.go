@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	snapshotQuiesce bool
+	snapshotOutput  string
+	forceRollback   bool
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "管理集群快照",
+	Long:  `为集群中所有节点虚拟机创建、列出、回滚和删除同名的 Proxmox 快照`,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "为所有节点创建快照",
+	Long: `为配置中的每个节点虚拟机创建同名快照，任一节点失败时删除已创建的快照。
+使用 --quiesce 先按工作节点、控制平面的顺序优雅关机，快照完成后再启动，得到文件系统一致的快照。`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSnapshotCreate,
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出集群快照",
+	RunE:  runSnapshotList,
+}
+
+var snapshotRollbackCmd = &cobra.Command{
+	Use:   "rollback <name>",
+	Short: "将所有节点回滚到快照",
+	Long: `停止所有节点，将每个节点回滚到同名快照，然后按控制平面优先的顺序启动。
+只有所有节点都拥有该快照时才会执行回滚。`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSnapshotRollback,
+}
+
+var snapshotDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "删除所有节点上的快照",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSnapshotDelete,
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotRollbackCmd)
+	snapshotCmd.AddCommand(snapshotDeleteCmd)
+
+	snapshotCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
+	snapshotCreateCmd.Flags().BoolVar(&snapshotQuiesce, "quiesce", false, "快照前优雅关闭节点，完成后重新启动")
+	snapshotListCmd.Flags().StringVarP(&snapshotOutput, "output", "o", "table", "输出格式: table、yaml 或 json")
+	snapshotRollbackCmd.Flags().BoolVarP(&forceRollback, "force", "f", false, "强制回滚，不询问确认")
+}
+
+func runSnapshotCreate(cmd *cobra.Command, args []string) error {
+	d, err := loadDeployer()
+	if err != nil {
+		return err
+	}
+	return d.CreateSnapshot(args[0], snapshotQuiesce)
+}
+
+func runSnapshotList(cmd *cobra.Command, args []string) error {
+	d, err := loadDeployer()
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := d.ListSnapshots()
+	if err != nil {
+		return err
+	}
+
+	switch snapshotOutput {
+	case "yaml":
+		data, err := yaml.Marshal(snapshots)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+	case "json":
+		data, err := json.MarshalIndent(snapshots, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "table":
+		if len(snapshots) == 0 {
+			fmt.Println("没有快照")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "名称\t创建时间\t节点\t说明")
+		for _, s := range snapshots {
+			coverage := fmt.Sprintf("%d/%d", len(s.Nodes), len(s.Nodes)+len(s.Missing))
+			if !s.Complete() {
+				coverage += "（缺少 " + strings.Join(s.Missing, ", ") + "）"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				s.Name, s.Time.Local().Format("2006-01-02 15:04:05"), coverage, s.Description)
+		}
+		w.Flush()
+	default:
+		return fmt.Errorf("无效的输出格式: %s，必须是 'table'、'yaml' 或 'json'", snapshotOutput)
+	}
+	return nil
+}
+
+func runSnapshotRollback(cmd *cobra.Command, args []string) error {
+	if !forceRollback {
+		if !confirm(fmt.Sprintf("确定要将所有节点回滚到快照 %s 吗？快照之后的所有变更都会丢失！(yes/no): ", args[0])) {
+			fmt.Println("操作已取消")
+			return nil
+		}
+	}
+
+	d, err := loadDeployer()
+	if err != nil {
+		return err
+	}
+	return d.RollbackSnapshot(args[0])
+}
+
+func runSnapshotDelete(cmd *cobra.Command, args []string) error {
+	d, err := loadDeployer()
+	if err != nil {
+		return err
+	}
+	return d.DeleteSnapshot(args[0])
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"talos-proxmox-deployer/pkg/config"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var stateOutput string
+
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "查看部署状态",
+	Long:  `查看部署器记录的实际创建的资源（<集群名>-config/state.yaml）`,
+}
+
+var stateShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "显示部署状态",
+	RunE:  runStateShow,
+}
+
+func init() {
+	stateCmd.AddCommand(stateShowCmd)
+
+	stateCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
+	stateShowCmd.Flags().StringVarP(&stateOutput, "output", "o", "table", "输出格式: table、yaml 或 json")
+}
+
+func runStateShow(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	d, err := newDeployer(cfg)
+	if err != nil {
+		return err
+	}
+
+	s, err := d.State()
+	if err != nil {
+		return err
+	}
+	if !s.Exists() {
+		return fmt.Errorf("未找到部署状态，集群尚未部署")
+	}
+
+	switch stateOutput {
+	case "yaml":
+		data, err := yaml.Marshal(s)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+	case "json":
+		data, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "table":
+		fmt.Printf("集群:       %s\n", s.ClusterName)
+		fmt.Printf("模板 VM ID: %d\n", s.TemplateVMID)
+		fmt.Printf("Talos 版本: %s\n", s.TalosVersion)
+		fmt.Printf("etcd 引导:  %v\n", s.Bootstrapped)
+		fmt.Printf("更新时间:   %s\n", s.UpdatedAt.Local().Format("2006-01-02 15:04:05"))
+		if len(s.CompletedSteps) > 0 {
+			fmt.Printf("已完成步骤: %s\n", strings.Join(s.CompletedSteps, ", "))
+		}
+		if s.Failure != nil {
+			fmt.Printf("上次失败:   步骤 %s", s.Failure.Step)
+			if s.Failure.Node != "" {
+				fmt.Printf("，节点 %s", s.Failure.Node)
+			}
+			fmt.Printf(": %s\n", s.Failure.Error)
+		}
+		fmt.Println()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "节点\t角色\tVM ID\tIP\tMAC\tPVE 节点\tTalos\t已创建\t已配置\t配置哈希")
+		for _, name := range s.NodeNames() {
+			n, _ := s.Lookup(name)
+			hash := n.ConfigHash
+			if len(hash) > 12 {
+				hash = hash[:12]
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%v\t%v\t%s\n",
+				name, n.Role, n.VMID, n.IPAddress, n.MAC, n.PVENode, n.TalosVersion, n.Created, n.Configured, hash)
+		}
+		w.Flush()
+	default:
+		return fmt.Errorf("无效的输出格式: %s，必须是 'table'、'yaml' 或 'json'", stateOutput)
+	}
+	return nil
+}
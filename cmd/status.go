@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"talos-proxmox-deployer/pkg/deployer"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var statusOutput string
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "查看集群节点状态",
+	Long: `每个节点一行，汇总 Proxmox 中的虚拟机状态和资源使用、Talos 版本和启动阶段、
+etcd 成员关系以及 Kubernetes 就绪状态和 kubelet 版本。`,
+	RunE: runStatus,
+}
+
+func init() {
+	statusCmd.Flags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
+	statusCmd.Flags().StringVarP(&statusOutput, "output", "o", "table", "输出格式: table、yaml 或 json")
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	if statusOutput != "table" && statusOutput != "yaml" && statusOutput != "json" {
+		return fmt.Errorf("无效的输出格式: %s，必须是 'table'、'yaml' 或 'json'", statusOutput)
+	}
+
+	d, err := loadDeployer()
+	if err != nil {
+		return err
+	}
+	statuses, err := d.NodeStatuses()
+	if err != nil {
+		return err
+	}
+
+	switch statusOutput {
+	case "yaml":
+		data, err := yaml.Marshal(statuses)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+	case "json":
+		data, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	default:
+		printStatusTable(statuses)
+	}
+	return nil
+}
+
+// printStatusTable 以表格输出节点状态，无法获取的字段显示为 -
+func printStatusTable(statuses []deployer.NodeStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "节点\t角色\tVM ID\tPVE 主机\t电源\tCPU\t内存\tTalos\t阶段\tetcd\tKubernetes\tkubelet")
+	for _, s := range statuses {
+		cpu, mem := "-", "-"
+		if s.Power == "running" {
+			cpu = fmt.Sprintf("%.0f%% / %d 核", s.CPUUsage*100, s.CPUs)
+			mem = fmt.Sprintf("%.1f / %.1f GiB", float64(s.MemUsed)/(1<<30), float64(s.MemTotal)/(1<<30))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			s.Name, s.Role, s.VMID, orDash(s.PVENode), s.Power, cpu, mem,
+			orDash(s.TalosVersion), s.TalosStage, orDash(s.Etcd), s.KubeReady, orDash(s.KubeletVersion))
+	}
+	w.Flush()
+}
+
+// orDash 空字符串显示为 -
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+
+	"talos-proxmox-deployer/pkg/config"
+	"talos-proxmox-deployer/pkg/deployer"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	upgradeVersion  string
+	upgradeNodes    []string
+	upgradeDryRun   bool
+	upgradeStage    bool
+	upgradeSnapshot bool
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "升级集群",
+	Long:  `滚动升级运行中集群的 Talos 或 Kubernetes 版本`,
+}
+
+var upgradeTalosCmd = &cobra.Command{
+	Use:   "talos",
+	Short: "滚动升级 Talos",
+	Long: `逐个节点执行 talosctl upgrade，控制平面在前，每个节点升级后等待节点和 etcd 恢复健康，任一节点失败立即停止。
+所有节点升级完成后更新配置文件中的 talos_version 和部署状态。`,
+	RunE: runUpgradeTalos,
+}
+
+var upgradeKubernetesCmd = &cobra.Command{
+	Use:   "kubernetes",
+	Short: "升级 Kubernetes 到配置的版本",
+	Long: `对比运行中的 Kubernetes 版本与配置中的 kubernetes_version，按 Talos 支持矩阵校验升级路径后，
+通过第一个可访问的控制平面执行 talosctl upgrade-k8s，并报告升级前后各组件的版本。`,
+	RunE: runUpgradeKubernetes,
+}
+
+func init() {
+	upgradeCmd.AddCommand(upgradeTalosCmd)
+	upgradeCmd.AddCommand(upgradeKubernetesCmd)
+
+	upgradeCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "cluster-config.yaml", "配置文件路径")
+	upgradeCmd.PersistentFlags().BoolVar(&upgradeDryRun, "dry-run", false, "只显示升级计划，不执行")
+	upgradeCmd.PersistentFlags().BoolVar(&upgradeSnapshot, "snapshot", false, "升级前为所有节点创建 Proxmox 快照（pre-upgrade-<时间>）")
+	upgradeTalosCmd.Flags().StringVar(&upgradeVersion, "to", "", "目标 Talos 版本（默认使用配置中的 talos_version）")
+	upgradeTalosCmd.Flags().StringSliceVarP(&upgradeNodes, "node", "n", nil, "只升级指定节点（可重复）")
+	upgradeTalosCmd.Flags().BoolVar(&upgradeStage, "stage", false, "暂存升级，在节点重启时安装")
+}
+
+func runUpgradeTalos(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("配置验证失败: %w", err)
+	}
+
+	d, err := newDeployer(cfg)
+	if err != nil {
+		return err
+	}
+
+	return d.UpgradeTalos(deployer.TalosUpgradeOptions{
+		Version:  upgradeVersion,
+		Nodes:    upgradeNodes,
+		DryRun:   upgradeDryRun,
+		Stage:    upgradeStage,
+		Snapshot: upgradeSnapshot,
+	})
+}
+
+func runUpgradeKubernetes(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("配置验证失败: %w", err)
+	}
+
+	d, err := newDeployer(cfg)
+	if err != nil {
+		return err
+	}
+
+	return d.UpgradeKubernetes(deployer.KubernetesUpgradeOptions{
+		DryRun:   upgradeDryRun,
+		Snapshot: upgradeSnapshot,
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 
 	"talos-proxmox-deployer/pkg/config"
-	"talos-proxmox-deployer/pkg/deployer"
 
 	"github.com/spf13/cobra"
 )
@@ -30,6 +29,9 @@ func runVerify(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("加载配置失败: %w", err)
 	}
 
-	d := deployer.New(cfg)
+	d, err := newDeployer(cfg)
+	if err != nil {
+		return err
+	}
 	return d.Verify()
 }
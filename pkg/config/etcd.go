@@ -0,0 +1,22 @@
+package config
+
+import "path/filepath"
+
+// DefaultEtcdRetention 默认保留的 etcd 备份数量
+const DefaultEtcdRetention = 7
+
+// EtcdBackupDir 返回 etcd 备份目录
+func (c *ClusterConfig) EtcdBackupDir() string {
+	if c.Etcd.BackupDir == "" {
+		return filepath.Join(c.ConfigDir(), "etcd-backups")
+	}
+	return c.ResolvePath(c.Etcd.BackupDir)
+}
+
+// EtcdRetention 返回保留的 etcd 备份数量
+func (c *ClusterConfig) EtcdRetention() int {
+	if c.Etcd.Retention == 0 {
+		return DefaultEtcdRetention
+	}
+	return c.Etcd.Retention
+}
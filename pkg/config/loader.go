@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"gopkg.in/yaml.v3"
 )
@@ -17,10 +18,42 @@ func Load(filename string) (*ClusterConfig, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("解析配置文件失败: %w", err)
 	}
+	cfg.path = filename
+	cfg.baseDir = filepath.Dir(filename)
+
+	if err := cfg.Nodes.assignRoles(); err != nil {
+		return nil, err
+	}
 
 	return &cfg, nil
 }
 
+// assignRoles 按节点所在的列表确定角色：control_planes 中为 controlplane，workers 中为 worker
+// role 字段可以省略，填写时必须与所在列表一致
+func (n *NodesConfig) assignRoles() error {
+	for i := range n.ControlPlanes {
+		if err := assignRole(&n.ControlPlanes[i], "controlplane", "control_planes"); err != nil {
+			return err
+		}
+	}
+	for i := range n.Workers {
+		if err := assignRole(&n.Workers[i], "worker", "workers"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignRole 设置节点角色，role 与所在列表不一致时返回错误
+func assignRole(node *NodeSpec, role, list string) error {
+	if node.Role != "" && node.Role != role {
+		return fmt.Errorf("节点 %s 位于 nodes.%s 中，但 role 为 %q，应为 %q 或省略", node.Name, list, node.Role, role)
+	}
+	node.Role = role
+	return nil
+}
+
+// Validate 检查配置，警告输出到标准错误，避免混入 plan -o json 等机器可读输出
 func (c *ClusterConfig) Validate() error {
 	if c.ClusterName == "" {
 		return fmt.Errorf("集群名称不能为空")
@@ -31,6 +64,48 @@ func (c *ClusterConfig) Validate() error {
 	if len(c.Nodes.ControlPlanes) < 1 {
 		return fmt.Errorf("至少需要 1 个控制平面节点")
 	}
+	if err := c.Nodes.assignRoles(); err != nil {
+		return err
+	}
+
+	// 验证 Talos 与 Kubernetes 版本兼容性
+	if c.KubernetesVersion != "" {
+		known, err := CheckKubernetesSupport(c.TalosVersion, c.KubernetesVersion)
+		if err != nil {
+			return err
+		}
+		if !known {
+			fmt.Fprintf(os.Stderr, "⚠️  警告: Talos %s 不在内置支持矩阵中，无法校验 Kubernetes %s 的兼容性\n",
+				c.TalosVersion, c.KubernetesVersion)
+		}
+	}
+
+	// 验证节点唯一性
+	names := make(map[string]bool)
+	ips := make(map[string]bool)
+	vmIDs := map[int]bool{c.Proxmox.TemplateVMID: true}
+	for _, node := range c.AllNodes() {
+		if node.Name == "" {
+			return fmt.Errorf("节点名称不能为空 (VM ID: %d)", node.VMID)
+		}
+		if names[node.Name] {
+			return fmt.Errorf("节点名称重复: %s", node.Name)
+		}
+		if ips[node.IPAddress] {
+			return fmt.Errorf("节点 IP 地址重复: %s", node.IPAddress)
+		}
+		if vmIDs[node.VMID] {
+			return fmt.Errorf("VM ID 重复: %d (%s)", node.VMID, node.Name)
+		}
+		names[node.Name] = true
+		ips[node.IPAddress] = true
+		vmIDs[node.VMID] = true
+	}
+
+	// 验证网络配置
+	if err := c.Network.validate(c.AllNodes()); err != nil {
+		return err
+	}
 
 	// 验证 Proxmox 认证配置
 	switch c.Proxmox.AuthMethod {
@@ -47,10 +122,78 @@ func (c *ClusterConfig) Validate() error {
 		if c.Proxmox.Password == "" && (c.Proxmox.APITokenID == "" || c.Proxmox.APIToken == "") {
 			return fmt.Errorf("必须配置 Proxmox 认证信息：设置 auth_method 并配置相应的认证凭据")
 		}
-		fmt.Println("⚠️  警告: 未指定 auth_method，建议明确设置为 'password' 或 'api_token'")
+		fmt.Fprintln(os.Stderr, "⚠️  警告: 未指定 auth_method，建议明确设置为 'password' 或 'api_token'")
 	default:
 		return fmt.Errorf("无效的 auth_method: %s，必须是 'password' 或 'api_token'", c.Proxmox.AuthMethod)
 	}
 
+	// 验证用户 patch
+	if err := c.validatePatches(); err != nil {
+		return err
+	}
+
+	switch c.Proxmox.Backend {
+	case "", "qm", "api":
+	default:
+		return fmt.Errorf("无效的 proxmox.backend: %s，必须是 'qm' 或 'api'", c.Proxmox.Backend)
+	}
+
+	if c.Deploy.Parallel < 0 {
+		return fmt.Errorf("deploy.parallel 不能为负数: %d", c.Deploy.Parallel)
+	}
+	if err := c.Deploy.Timeouts.validate(); err != nil {
+		return err
+	}
+	if c.Etcd.Retention < 0 {
+		return fmt.Errorf("etcd.retention 不能为负数: %d", c.Etcd.Retention)
+	}
+
 	return nil
 }
+
+// AllNodes 返回所有节点，控制平面在前
+func (c *ClusterConfig) AllNodes() []NodeSpec {
+	nodes := make([]NodeSpec, 0, len(c.Nodes.ControlPlanes)+len(c.Nodes.Workers))
+	nodes = append(nodes, c.Nodes.ControlPlanes...)
+	return append(nodes, c.Nodes.Workers...)
+}
+
+// ConfigDir 返回集群配置目录
+func (c *ClusterConfig) ConfigDir() string {
+	return fmt.Sprintf("./%s-config", c.ClusterName)
+}
+
+// FindNode 按名称查找节点
+func (c *ClusterConfig) FindNode(name string) (NodeSpec, bool) {
+	for _, node := range c.AllNodes() {
+		if node.Name == name {
+			return node, true
+		}
+	}
+	return NodeSpec{}, false
+}
+
+// DefaultInstallerImage Talos 官方安装镜像仓库
+const DefaultInstallerImage = "ghcr.io/siderolabs/installer"
+
+// InstallImage 返回指定 Talos 版本的安装镜像，如 ghcr.io/siderolabs/installer:v1.6.0
+func (c *ClusterConfig) InstallImage(talosVersion string) string {
+	repo := c.InstallerImage
+	if repo == "" {
+		repo = DefaultInstallerImage
+	}
+	return repo + ":" + talosVersion
+}
+
+// ControlPlaneHost 返回 Kubernetes API 端点地址：配置了 VIP 时使用 VIP，否则使用第一个控制平面
+func (c *ClusterConfig) ControlPlaneHost() string {
+	if c.Network.VIP != "" {
+		return c.Network.VIP
+	}
+	return c.Nodes.ControlPlanes[0].IPAddress
+}
+
+// ControlPlaneEndpoint 返回 Kubernetes API 端点 URL
+func (c *ClusterConfig) ControlPlaneEndpoint() string {
+	return fmt.Sprintf("https://%s:6443", c.ControlPlaneHost())
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// PrefixLength 返回子网掩码位数
+func (n NetworkConfig) PrefixLength() (int, error) {
+	bits, err := strconv.Atoi(strings.TrimPrefix(n.Netmask, "/"))
+	if err != nil || bits < 0 || bits > 32 {
+		return 0, fmt.Errorf("无效的子网掩码位数: %q", n.Netmask)
+	}
+	return bits, nil
+}
+
+// Nameservers 返回 DNS 服务器列表，dns_server 支持逗号分隔多个地址
+func (n NetworkConfig) Nameservers() []string {
+	var servers []string
+	for _, s := range strings.Split(n.DNSServer, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			servers = append(servers, s)
+		}
+	}
+	return servers
+}
+
+// NodeCIDR 返回节点地址的 CIDR 表示，如 192.168.1.101/24
+func (n NetworkConfig) NodeCIDR(node NodeSpec) (string, error) {
+	bits, err := n.PrefixLength()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%d", node.IPAddress, bits), nil
+}
+
+// validate 检查网络配置和所有节点地址
+func (n NetworkConfig) validate(nodes []NodeSpec) error {
+	if _, err := n.PrefixLength(); err != nil {
+		return err
+	}
+	if net.ParseIP(n.Gateway).To4() == nil {
+		return fmt.Errorf("无效的网关地址: %q", n.Gateway)
+	}
+	for _, s := range n.Nameservers() {
+		if net.ParseIP(s) == nil {
+			return fmt.Errorf("无效的 DNS 服务器地址: %q", s)
+		}
+	}
+
+	for _, node := range nodes {
+		if net.ParseIP(node.IPAddress).To4() == nil {
+			return fmt.Errorf("节点 %s 的 IP 地址无效: %q", node.Name, node.IPAddress)
+		}
+	}
+
+	if n.VIP != "" {
+		if err := n.validateVIP(nodes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subnet 返回由网关和子网掩码确定的节点子网
+func (n NetworkConfig) Subnet() (*net.IPNet, error) {
+	bits, err := n.PrefixLength()
+	if err != nil {
+		return nil, err
+	}
+	_, subnet, err := net.ParseCIDR(fmt.Sprintf("%s/%d", n.Gateway, bits))
+	if err != nil {
+		return nil, fmt.Errorf("无效的网关地址: %q", n.Gateway)
+	}
+	return subnet, nil
+}
+
+// validateVIP 检查 VIP 位于节点子网内且未被占用
+func (n NetworkConfig) validateVIP(nodes []NodeSpec) error {
+	vip := net.ParseIP(n.VIP)
+	if vip.To4() == nil {
+		return fmt.Errorf("无效的 VIP 地址: %q", n.VIP)
+	}
+
+	subnet, err := n.Subnet()
+	if err != nil {
+		return err
+	}
+	if !subnet.Contains(vip) {
+		return fmt.Errorf("VIP %s 不在节点子网 %s 内", n.VIP, subnet)
+	}
+	if vip.Equal(net.ParseIP(n.Gateway)) {
+		return fmt.Errorf("VIP %s 与网关地址冲突", n.VIP)
+	}
+	for _, node := range nodes {
+		if vip.Equal(net.ParseIP(node.IPAddress)) {
+			return fmt.Errorf("VIP %s 已分配给节点 %s", n.VIP, node.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ResolvePath 将相对路径解析为基于配置文件所在目录的路径
+func (c *ClusterConfig) ResolvePath(p string) string {
+	if p == "" || filepath.IsAbs(p) || c.baseDir == "" {
+		return p
+	}
+	return filepath.Join(c.baseDir, p)
+}
+
+// NodePatches 返回适用于节点的用户 patch，顺序为 cluster → 角色 → 节点
+func (c *ClusterConfig) NodePatches(node NodeSpec) []Patch {
+	patches := append([]Patch{}, c.Patches.Cluster...)
+	if node.Role == "controlplane" {
+		patches = append(patches, c.Patches.ControlPlane...)
+	} else {
+		patches = append(patches, c.Patches.Worker...)
+	}
+	return append(patches, c.Patches.Nodes[node.Name]...)
+}
+
+// validatePatches 检查 patch 格式、文件和节点引用
+func (c *ClusterConfig) validatePatches() error {
+	check := func(scope string, patches []Patch) error {
+		for i, p := range patches {
+			switch {
+			case p.Inline != "" && p.File != "":
+				return fmt.Errorf("patches.%s[%d]: inline 和 file 只能设置一个", scope, i)
+			case p.Inline != "":
+				var v interface{}
+				if err := yaml.Unmarshal([]byte(p.Inline), &v); err != nil {
+					return fmt.Errorf("patches.%s[%d]: 内联 YAML 无效: %w", scope, i, err)
+				}
+			case p.File != "":
+				if _, err := os.Stat(c.ResolvePath(p.File)); err != nil {
+					return fmt.Errorf("patches.%s[%d]: patch 文件不存在: %s", scope, i, p.File)
+				}
+			default:
+				return fmt.Errorf("patches.%s[%d]: 必须设置 inline 或 file", scope, i)
+			}
+		}
+		return nil
+	}
+
+	if err := check("cluster", c.Patches.Cluster); err != nil {
+		return err
+	}
+	if err := check("controlplane", c.Patches.ControlPlane); err != nil {
+		return err
+	}
+	if err := check("worker", c.Patches.Worker); err != nil {
+		return err
+	}
+	for name, patches := range c.Patches.Nodes {
+		if _, ok := c.FindNode(name); !ok {
+			return fmt.Errorf("patches.nodes: 未知节点 %s", name)
+		}
+		if err := check("nodes."+name, patches); err != nil {
+			return err
+		}
+	}
+	return nil
+}
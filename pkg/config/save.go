@@ -0,0 +1,168 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Save 将配置写回加载时的文件
+// 在原文件的 YAML 节点树上合并修改，保留注释、键顺序和引号风格
+func (c *ClusterConfig) Save() error {
+	if c.path == "" {
+		return fmt.Errorf("配置未从文件加载，无法保存")
+	}
+
+	var updated yaml.Node
+	if err := updated.Encode(c); err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("解析配置文件失败: %w", err)
+	}
+
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&updated}}
+	} else {
+		mergeNode(doc.Content[0], &updated)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("序列化配置失败: %w", err)
+	}
+	enc.Close()
+	out := separateSections(buf.Bytes())
+
+	// 先写临时文件再替换，避免写入中断损坏配置
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, out, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("写入配置文件失败: %w", err)
+	}
+	return nil
+}
+
+// separateSections 在顶层配置段之间插入空行
+// YAML 节点树不记录空行，按示例配置的风格在缩进内容之后的顶层键前补一个空行
+func separateSections(data []byte) []byte {
+	lines := bytes.Split(data, []byte("\n"))
+	var out [][]byte
+	for i, line := range lines {
+		if i > 0 && len(line) > 0 && line[0] != ' ' && line[0] != '-' {
+			if prev := lines[i-1]; len(prev) > 0 && (prev[0] == ' ' || prev[0] == '-') {
+				out = append(out, nil)
+			}
+		}
+		out = append(out, line)
+	}
+	return bytes.Join(out, []byte("\n"))
+}
+
+// mergeNode 将 src 的值合并到 dst，dst 上已有的注释和风格保持不变
+func mergeNode(dst, src *yaml.Node) {
+	if dst.Kind != src.Kind {
+		comments := [3]string{dst.HeadComment, dst.LineComment, dst.FootComment}
+		*dst = *src
+		dst.HeadComment, dst.LineComment, dst.FootComment = comments[0], comments[1], comments[2]
+		return
+	}
+
+	switch dst.Kind {
+	case yaml.MappingNode:
+		mergeMapping(dst, src)
+	case yaml.SequenceNode:
+		for i, item := range src.Content {
+			if i < len(dst.Content) {
+				mergeNode(dst.Content[i], item)
+			} else {
+				dst.Content = append(dst.Content, item)
+			}
+		}
+		if len(dst.Content) > len(src.Content) {
+			dst.Content = dst.Content[:len(src.Content)]
+		}
+		if len(dst.Content) == 0 {
+			dst.Style = yaml.FlowStyle
+		}
+	case yaml.ScalarNode:
+		if dst.Value == src.Value && dst.ShortTag() == src.ShortTag() {
+			return
+		}
+		dst.Value = src.Value
+		dst.Tag = src.Tag
+		// 原来带引号的字符串继续使用引号
+		if src.ShortTag() != "!!str" || dst.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) == 0 {
+			dst.Style = src.Style
+		}
+	}
+}
+
+// mergeMapping 合并映射：更新已有键，追加新键，删除 src 中已不存在的键
+// omitempty 字段序列化时会被省略，原文件中这类零值键予以保留
+func mergeMapping(dst, src *yaml.Node) {
+	srcKeys := make(map[string]*yaml.Node, len(src.Content)/2)
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		srcKeys[src.Content[i].Value] = src.Content[i+1]
+	}
+
+	var content []*yaml.Node
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		key, value := dst.Content[i], dst.Content[i+1]
+		seen[key.Value] = true
+
+		srcValue, ok := srcKeys[key.Value]
+		if !ok {
+			if isZeroNode(value) {
+				content = append(content, key, value)
+			}
+			continue
+		}
+		mergeNode(value, srcValue)
+		content = append(content, key, value)
+	}
+
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		if !seen[src.Content[i].Value] {
+			content = append(content, src.Content[i], src.Content[i+1])
+		}
+	}
+	dst.Content = content
+}
+
+// isZeroNode 判断节点是否为零值（空字符串、0、false、null 或空集合）
+func isZeroNode(n *yaml.Node) bool {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!null":
+			return true
+		case "!!str":
+			return n.Value == ""
+		case "!!int", "!!float":
+			return n.Value == "0"
+		case "!!bool":
+			return n.Value == "false"
+		}
+	case yaml.MappingNode, yaml.SequenceNode:
+		return len(n.Content) == 0
+	}
+	return false
+}
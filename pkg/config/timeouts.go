@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// 等待各阶段就绪的默认超时
+const (
+	DefaultVMRunningTimeout     = 2 * time.Minute
+	DefaultTalosAPITimeout      = 5 * time.Minute
+	DefaultConfiguredTimeout    = 10 * time.Minute
+	DefaultEtcdTimeout          = 10 * time.Minute
+	DefaultKubernetesAPITimeout = 10 * time.Minute
+	DefaultUpgradeTimeout       = 15 * time.Minute
+	DefaultNodeReadyTimeout     = 10 * time.Minute
+	DefaultShutdownTimeout      = 5 * time.Minute
+	DefaultRebootTimeout        = 10 * time.Minute
+)
+
+// VMRunningTimeout 等待虚拟机在 Proxmox 中进入运行状态的超时
+func (t TimeoutsConfig) VMRunningTimeout() time.Duration {
+	return parseTimeout(t.VMRunning, DefaultVMRunningTimeout)
+}
+
+// TalosAPITimeout 等待 Talos API 端口 50000 响应的超时
+func (t TimeoutsConfig) TalosAPITimeout() time.Duration {
+	return parseTimeout(t.TalosAPI, DefaultTalosAPITimeout)
+}
+
+// ConfiguredTimeout 等待节点应用配置后退出维护模式的超时
+func (t TimeoutsConfig) ConfiguredTimeout() time.Duration {
+	return parseTimeout(t.Configured, DefaultConfiguredTimeout)
+}
+
+// EtcdTimeout 等待 etcd 健康的超时
+func (t TimeoutsConfig) EtcdTimeout() time.Duration {
+	return parseTimeout(t.Etcd, DefaultEtcdTimeout)
+}
+
+// KubernetesAPITimeout 等待 Kubernetes API 响应的超时
+func (t TimeoutsConfig) KubernetesAPITimeout() time.Duration {
+	return parseTimeout(t.KubernetesAPI, DefaultKubernetesAPITimeout)
+}
+
+// UpgradeTimeout 等待单个节点升级完成的超时
+func (t TimeoutsConfig) UpgradeTimeout() time.Duration {
+	return parseTimeout(t.Upgrade, DefaultUpgradeTimeout)
+}
+
+// NodeReadyTimeout 等待新节点在 Kubernetes 中 Ready 的超时
+func (t TimeoutsConfig) NodeReadyTimeout() time.Duration {
+	return parseTimeout(t.NodeReady, DefaultNodeReadyTimeout)
+}
+
+// ShutdownTimeout 等待节点优雅关机的超时
+func (t TimeoutsConfig) ShutdownTimeout() time.Duration {
+	return parseTimeout(t.Shutdown, DefaultShutdownTimeout)
+}
+
+// RebootTimeout 等待节点重启完成的超时
+func (t TimeoutsConfig) RebootTimeout() time.Duration {
+	return parseTimeout(t.Reboot, DefaultRebootTimeout)
+}
+
+// parseTimeout 解析超时，未设置时使用默认值，格式已在 validate 中校验
+func parseTimeout(value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return def
+	}
+	return d
+}
+
+// validate 校验超时格式
+func (t TimeoutsConfig) validate() error {
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"vm_running", t.VMRunning},
+		{"talos_api", t.TalosAPI},
+		{"configured", t.Configured},
+		{"etcd", t.Etcd},
+		{"kubernetes_api", t.KubernetesAPI},
+		{"upgrade", t.Upgrade},
+		{"node_ready", t.NodeReady},
+		{"shutdown", t.Shutdown},
+		{"reboot", t.Reboot},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(f.value)
+		if err != nil {
+			return fmt.Errorf("无效的 deploy.timeouts.%s: %s，格式如 90s、5m", f.name, f.value)
+		}
+		if d <= 0 {
+			return fmt.Errorf("deploy.timeouts.%s 必须大于 0: %s", f.name, f.value)
+		}
+	}
+	return nil
+}
@@ -4,11 +4,20 @@ type ClusterConfig struct {
 	ClusterName       string          `yaml:"cluster_name"`
 	TalosVersion      string          `yaml:"talos_version"`
 	KubernetesVersion string          `yaml:"kubernetes_version"`
+	InstallerImage    string          `yaml:"installer_image,omitempty"` // Talos 安装镜像仓库，默认 ghcr.io/siderolabs/installer
 	Network           NetworkConfig   `yaml:"network"`
 	Proxmox           ProxmoxConfig   `yaml:"proxmox"`
 	Nodes             NodesConfig     `yaml:"nodes"`
 	Proxy             ProxyConfig     `yaml:"proxy,omitempty"`
 	Registry          *RegistryConfig `yaml:"registry,omitempty"` // 容器镜像仓库配置
+	Patches           PatchesConfig   `yaml:"patches,omitempty"`  // Talos 机器配置 patch
+	Deploy            DeployConfig    `yaml:"deploy,omitempty"`   // 部署过程配置
+	Etcd              EtcdConfig      `yaml:"etcd,omitempty"`     // etcd 备份配置
+
+	// path 配置文件路径，用于 Save 写回
+	path string
+	// baseDir 配置文件所在目录，用于解析相对路径
+	baseDir string
 }
 
 type NetworkConfig struct {
@@ -16,16 +25,21 @@ type NetworkConfig struct {
 	DNSServer string `yaml:"dns_server"`
 	Gateway   string `yaml:"gateway"`
 	Netmask   string `yaml:"netmask"`
+	VIP       string `yaml:"vip,omitempty"` // 控制平面共享虚拟 IP，作为 Kubernetes API 端点
 }
 
 type ProxmoxConfig struct {
 	Host          string `yaml:"host"`
+	Port          int    `yaml:"port,omitempty"`    // API 端口，默认 8006
+	Node          string `yaml:"node,omitempty"`    // Proxmox 节点名，单节点环境可留空自动探测
+	Backend       string `yaml:"backend,omitempty"` // 操作方式: "qm"（本机命令）或 "api"（REST API），留空自动选择
 	User          string `yaml:"user"`
 	AuthMethod    string `yaml:"auth_method"`            // 认证方式: "password" 或 "api_token"
 	Password      string `yaml:"password,omitempty"`     // 密码认证（不推荐）
 	APITokenID    string `yaml:"api_token_id,omitempty"` // API Token ID（推荐）
 	APIToken      string `yaml:"api_token,omitempty"`    // API Token Secret（推荐）
 	StoragePool   string `yaml:"storage_pool"`
+	ImportStorage string `yaml:"import_storage,omitempty"` // API 方式上传镜像的存储（需支持 import 内容），默认 local
 	TemplateVMID  int    `yaml:"template_vm_id"`
 	SkipTLSVerify bool   `yaml:"skip_tls_verify,omitempty"` // 跳过 TLS 验证（开发环境）
 }
@@ -43,6 +57,8 @@ type NodeSpec struct {
 	Memory    int    `yaml:"memory"`
 	Disk      string `yaml:"disk"`
 	Role      string `yaml:"role"`
+
+	Labels map[string]string `yaml:"labels,omitempty"` // Kubernetes 节点标签
 }
 
 type ProxyConfig struct {
@@ -53,6 +69,31 @@ type ProxyConfig struct {
 	MirrorURL  string `yaml:"mirror_url,omitempty"` // 用于 Talos 镜像下载的镜像站
 }
 
+// DeployConfig 部署过程配置
+type DeployConfig struct {
+	Parallel int            `yaml:"parallel,omitempty"` // 同时创建和配置的节点数，默认 1（逐个进行）
+	Timeouts TimeoutsConfig `yaml:"timeouts,omitempty"` // 等待各阶段就绪的超时
+}
+
+// EtcdConfig etcd 备份配置
+type EtcdConfig struct {
+	BackupDir string `yaml:"backup_dir,omitempty"` // 备份目录，默认 <集群名>-config/etcd-backups，相对路径基于配置文件所在目录
+	Retention int    `yaml:"retention,omitempty"`  // 保留的备份数量，默认 7
+}
+
+// TimeoutsConfig 等待各阶段就绪的超时，格式如 "90s"、"5m"，留空使用默认值
+type TimeoutsConfig struct {
+	VMRunning     string `yaml:"vm_running,omitempty"`     // 虚拟机进入运行状态，默认 2m
+	TalosAPI      string `yaml:"talos_api,omitempty"`      // Talos API 端口 50000 响应，默认 5m
+	Configured    string `yaml:"configured,omitempty"`     // 应用配置后节点退出维护模式，默认 10m
+	Etcd          string `yaml:"etcd,omitempty"`           // etcd 健康，默认 10m
+	KubernetesAPI string `yaml:"kubernetes_api,omitempty"` // Kubernetes API 在 6443 端口响应，默认 10m
+	Upgrade       string `yaml:"upgrade,omitempty"`        // 单个节点升级完成并运行新版本，默认 15m
+	NodeReady     string `yaml:"node_ready,omitempty"`     // 新节点加入集群并在 Kubernetes 中 Ready，默认 10m
+	Shutdown      string `yaml:"shutdown,omitempty"`       // 节点优雅关机，超时后改用 ACPI，ACPI 再超时后强制停止，默认 5m
+	Reboot        string `yaml:"reboot,omitempty"`         // 滚动重启时节点重启完成，默认 10m
+}
+
 // RegistryConfig 容器镜像仓库配置
 type RegistryConfig struct {
 	Mirrors map[string]RegistryMirror `yaml:"mirrors,omitempty"` // 镜像源配置
@@ -62,3 +103,17 @@ type RegistryConfig struct {
 type RegistryMirror struct {
 	Endpoints []string `yaml:"endpoints"` // 镜像源地址列表
 }
+
+// PatchesConfig Talos 机器配置 patch，按 cluster → 角色 → 节点 的顺序应用
+type PatchesConfig struct {
+	Cluster      []Patch            `yaml:"cluster,omitempty"`      // 所有节点
+	ControlPlane []Patch            `yaml:"controlplane,omitempty"` // 控制平面节点
+	Worker       []Patch            `yaml:"worker,omitempty"`       // 工作节点
+	Nodes        map[string][]Patch `yaml:"nodes,omitempty"`        // 按节点名
+}
+
+// Patch 一条 strategic merge patch，inline 与 file 二选一
+type Patch struct {
+	Inline string `yaml:"inline,omitempty"` // 内联 YAML
+	File   string `yaml:"file,omitempty"`   // patch 文件路径，相对路径基于配置文件所在目录
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// talosKubernetesSupport Talos 版本（minor）支持的 Kubernetes 版本范围（含两端）
+// 参考 https://www.talos.dev/ 各版本的 Support Matrix
+var talosKubernetesSupport = map[string][2]string{
+	"1.3":  {"1.21", "1.26"},
+	"1.4":  {"1.22", "1.27"},
+	"1.5":  {"1.23", "1.28"},
+	"1.6":  {"1.24", "1.29"},
+	"1.7":  {"1.25", "1.30"},
+	"1.8":  {"1.26", "1.31"},
+	"1.9":  {"1.27", "1.32"},
+	"1.10": {"1.28", "1.33"},
+	"1.11": {"1.29", "1.34"},
+}
+
+// Version 语义化版本号
+type Version struct {
+	Major, Minor, Patch int
+	HasPatch            bool
+}
+
+// ParseVersion 解析 "v1.6.0"、"1.29" 或 "1.29.3" 形式的版本号
+func ParseVersion(v string) (Version, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(v), "v")
+	// 去掉预发布后缀，如 1.7.0-beta.0
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Version{}, fmt.Errorf("无效的版本号: %q", v)
+	}
+
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("无效的版本号: %q", v)
+		}
+		nums[i] = n
+	}
+
+	ver := Version{Major: nums[0], Minor: nums[1]}
+	if len(nums) == 3 {
+		ver.Patch = nums[2]
+		ver.HasPatch = true
+	}
+	return ver, nil
+}
+
+// MinorString 返回 "major.minor" 形式
+func (v Version) MinorString() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// String 返回 "major.minor.patch" 形式
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// CompareMinor 比较 major.minor，返回 -1、0 或 1
+func (v Version) CompareMinor(o Version) int {
+	switch {
+	case v.Major != o.Major:
+		if v.Major < o.Major {
+			return -1
+		}
+		return 1
+	case v.Minor < o.Minor:
+		return -1
+	case v.Minor > o.Minor:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Compare 比较完整版本号，返回 -1、0 或 1
+func (v Version) Compare(o Version) int {
+	if c := v.CompareMinor(o); c != 0 {
+		return c
+	}
+	switch {
+	case v.Patch < o.Patch:
+		return -1
+	case v.Patch > o.Patch:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// SupportedKubernetesRange 返回 Talos 版本支持的 Kubernetes 范围，未知版本返回 ok=false
+func SupportedKubernetesRange(talosVersion string) (min, max string, ok bool, err error) {
+	tv, err := ParseVersion(talosVersion)
+	if err != nil {
+		return "", "", false, err
+	}
+	r, ok := talosKubernetesSupport[tv.MinorString()]
+	if !ok {
+		return "", "", false, nil
+	}
+	return r[0], r[1], true, nil
+}
+
+// CheckKubernetesSupport 检查 Talos 版本是否支持指定的 Kubernetes 版本
+// Talos 版本不在内置支持矩阵中时返回 known=false 且不报错
+func CheckKubernetesSupport(talosVersion, kubernetesVersion string) (known bool, err error) {
+	min, max, known, err := SupportedKubernetesRange(talosVersion)
+	if err != nil {
+		return false, fmt.Errorf("Talos 版本无效: %w", err)
+	}
+	kv, err := ParseVersion(kubernetesVersion)
+	if err != nil {
+		return known, fmt.Errorf("Kubernetes 版本无效: %w", err)
+	}
+	if !known {
+		return false, nil
+	}
+
+	minV, _ := ParseVersion(min)
+	maxV, _ := ParseVersion(max)
+	if kv.CompareMinor(minV) < 0 || kv.CompareMinor(maxV) > 0 {
+		return true, fmt.Errorf("Talos %s 不支持 Kubernetes %s（支持范围: %s - %s）",
+			talosVersion, kubernetesVersion, min, max)
+	}
+	return true, nil
+}
+
+// KubernetesVersionFull 返回传给 talosctl 的完整 Kubernetes 版本号
+// 只指定 major.minor 时补全为 .0
+func (c *ClusterConfig) KubernetesVersionFull() string {
+	v, err := ParseVersion(c.KubernetesVersion)
+	if err != nil {
+		return c.KubernetesVersion
+	}
+	return v.String()
+}
@@ -0,0 +1,82 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"talos-proxmox-deployer/pkg/config"
+	"talos-proxmox-deployer/pkg/proxmox"
+)
+
+// ErrVMNotFound 虚拟机不存在
+var ErrVMNotFound = errors.New("虚拟机不存在")
+
+// VMOptions 虚拟机选项，键名与 qm 命令行选项一致（不含 --）
+type VMOptions map[string]string
+
+// VMStatus 虚拟机状态
+type VMStatus = proxmox.VMStatus
+
+// Snapshot 虚拟机快照
+type Snapshot = proxmox.Snapshot
+
+// ProxmoxBackend 抽象部署器对 Proxmox 的所有操作
+type ProxmoxBackend interface {
+	// CreateVM 创建虚拟机
+	CreateVM(vmID int, opts VMOptions) error
+	// CloneVM 从模板完整克隆虚拟机
+	CloneVM(srcID, dstID int, name string) error
+	// SetVMOptions 修改虚拟机配置
+	SetVMOptions(vmID int, opts VMOptions) error
+	// ResizeDisk 将磁盘扩容到指定大小（如 "50G"）
+	ResizeDisk(vmID int, disk, size string) error
+	// StartVM 启动虚拟机
+	StartVM(vmID int) error
+	// StopVM 立即停止虚拟机
+	StopVM(vmID int) error
+	// ShutdownVM 通过 ACPI 优雅关机，超时后强制停止
+	ShutdownVM(vmID int, timeout time.Duration) error
+	// DestroyVM 删除虚拟机及其磁盘
+	DestroyVM(vmID int) error
+	// VMStatus 获取虚拟机状态，不存在时返回 ErrVMNotFound
+	VMStatus(vmID int) (*VMStatus, error)
+	// VMConfig 获取虚拟机配置，不存在时返回 ErrVMNotFound
+	VMConfig(vmID int) (map[string]string, error)
+	// ListVMs 列出 Proxmox 节点上的所有虚拟机（含模板），按 VM ID 排序
+	ListVMs() ([]VMStatus, error)
+	// ImportDisk 导入磁盘镜像并挂载为 disk，diskOpts 为附加磁盘选项
+	ImportDisk(vmID int, disk, imagePath, storage, diskOpts string) error
+	// ConvertToTemplate 将虚拟机转换为模板
+	ConvertToTemplate(vmID int) error
+	// NodeName 返回虚拟机所在的 Proxmox 节点名
+	NodeName() (string, error)
+
+	// CreateSnapshot 创建快照，vmstate 为 true 时同时保存内存状态
+	CreateSnapshot(vmID int, name, description string, vmstate bool) error
+	// ListSnapshots 列出虚拟机快照，按创建时间排序
+	ListSnapshots(vmID int) ([]Snapshot, error)
+	// RollbackSnapshot 将虚拟机回滚到快照
+	RollbackSnapshot(vmID int, name string) error
+	// DeleteSnapshot 删除快照
+	DeleteSnapshot(vmID int, name string) error
+}
+
+// NewBackend 根据配置选择 Proxmox 后端
+// backend 为空时，若本机存在 qm 命令则使用 qm，否则使用 REST API
+func NewBackend(cfg *config.ClusterConfig) (ProxmoxBackend, error) {
+	switch cfg.Proxmox.Backend {
+	case "qm":
+		return NewQMBackend(cfg.Proxmox), nil
+	case "api":
+		return NewAPIBackend(cfg.Proxmox)
+	case "":
+		if _, err := exec.LookPath("qm"); err == nil {
+			return NewQMBackend(cfg.Proxmox), nil
+		}
+		return NewAPIBackend(cfg.Proxmox)
+	default:
+		return nil, fmt.Errorf("无效的 Proxmox 后端: %s，必须是 'qm' 或 'api'", cfg.Proxmox.Backend)
+	}
+}
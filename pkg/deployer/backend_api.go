@@ -0,0 +1,135 @@
+package deployer
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"talos-proxmox-deployer/pkg/config"
+	"talos-proxmox-deployer/pkg/proxmox"
+)
+
+// APIBackend 通过 Proxmox VE REST API 操作虚拟机，可在任意机器上运行
+type APIBackend struct {
+	client        *proxmox.Client
+	importStorage string
+}
+
+// NewAPIBackend 创建 REST API 后端
+func NewAPIBackend(cfg config.ProxmoxConfig) (*APIBackend, error) {
+	client, err := proxmox.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	importStorage := cfg.ImportStorage
+	if importStorage == "" {
+		importStorage = "local"
+	}
+	return &APIBackend{client: client, importStorage: importStorage}, nil
+}
+
+// wrap 将 API 的"不存在"错误转换为 ErrVMNotFound
+func (b *APIBackend) wrap(err error) error {
+	if proxmox.IsNotFound(err) {
+		return ErrVMNotFound
+	}
+	return err
+}
+
+func (b *APIBackend) CreateVM(vmID int, opts VMOptions) error {
+	return b.client.CreateVM(vmID, opts)
+}
+
+func (b *APIBackend) CloneVM(srcID, dstID int, name string) error {
+	return b.client.CloneVM(srcID, dstID, name, true)
+}
+
+func (b *APIBackend) SetVMOptions(vmID int, opts VMOptions) error {
+	return b.wrap(b.client.SetVMConfig(vmID, opts))
+}
+
+func (b *APIBackend) ResizeDisk(vmID int, disk, size string) error {
+	return b.wrap(b.client.ResizeDisk(vmID, disk, size))
+}
+
+func (b *APIBackend) StartVM(vmID int) error {
+	return b.wrap(b.client.StartVM(vmID))
+}
+
+func (b *APIBackend) StopVM(vmID int) error {
+	return b.wrap(b.client.StopVM(vmID))
+}
+
+func (b *APIBackend) ShutdownVM(vmID int, timeout time.Duration) error {
+	return b.wrap(b.client.ShutdownVM(vmID, timeout))
+}
+
+func (b *APIBackend) DestroyVM(vmID int) error {
+	return b.wrap(b.client.DeleteVM(vmID))
+}
+
+func (b *APIBackend) VMStatus(vmID int) (*VMStatus, error) {
+	status, err := b.client.GetVMStatus(vmID)
+	if err != nil {
+		return nil, b.wrap(err)
+	}
+	return status, nil
+}
+
+func (b *APIBackend) ListVMs() ([]VMStatus, error) {
+	vms, err := b.client.ListVMs()
+	if err != nil {
+		return nil, b.wrap(err)
+	}
+	return vms, nil
+}
+
+func (b *APIBackend) VMConfig(vmID int) (map[string]string, error) {
+	cfg, err := b.client.GetVMConfig(vmID)
+	if err != nil {
+		return nil, b.wrap(err)
+	}
+	return cfg, nil
+}
+
+func (b *APIBackend) ImportDisk(vmID int, disk, imagePath, storage, diskOpts string) error {
+	// 先上传到支持 import 内容类型的存储，再通过 import-from 导入
+	if err := b.client.Upload(b.importStorage, "import", imagePath); err != nil {
+		return err
+	}
+
+	spec := fmt.Sprintf("%s:0,import-from=%s:import/%s", storage, b.importStorage, filepath.Base(imagePath))
+	if diskOpts != "" {
+		spec += "," + diskOpts
+	}
+	return b.SetVMOptions(vmID, VMOptions{disk: spec})
+}
+
+func (b *APIBackend) ConvertToTemplate(vmID int) error {
+	return b.wrap(b.client.ConvertToTemplate(vmID))
+}
+
+func (b *APIBackend) NodeName() (string, error) {
+	return b.client.Node()
+}
+
+func (b *APIBackend) CreateSnapshot(vmID int, name, description string, vmstate bool) error {
+	return b.wrap(b.client.CreateSnapshot(vmID, name, description, vmstate))
+}
+
+func (b *APIBackend) ListSnapshots(vmID int) ([]Snapshot, error) {
+	snapshots, err := b.client.ListSnapshots(vmID)
+	if err != nil {
+		return nil, b.wrap(err)
+	}
+	return snapshots, nil
+}
+
+func (b *APIBackend) RollbackSnapshot(vmID int, name string) error {
+	return b.wrap(b.client.RollbackSnapshot(vmID, name))
+}
+
+func (b *APIBackend) DeleteSnapshot(vmID int, name string) error {
+	return b.wrap(b.client.DeleteSnapshot(vmID, name))
+}
@@ -0,0 +1,431 @@
+package deployer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// FakeVM 内存后端中的虚拟机
+type FakeVM struct {
+	Name     string
+	Status   string
+	Template bool
+	Disk     int64 // scsi0 大小（字节）
+	Options  VMOptions
+
+	Snapshots []FakeSnapshot
+}
+
+// FakeSnapshot 内存后端中的快照，保存创建时的状态以便回滚
+type FakeSnapshot struct {
+	Snapshot
+	Status  string
+	Disk    int64
+	Options VMOptions
+}
+
+// FakeBackend 内存中的 Proxmox 后端，记录所有调用并模拟虚拟机状态，用于测试
+type FakeBackend struct {
+	mu    sync.Mutex
+	VMs   map[int]*FakeVM
+	Calls []string
+
+	// Errors 按操作名注入错误，如 "clone"、"start"
+	Errors map[string]error
+}
+
+// NewFakeBackend 创建内存后端
+func NewFakeBackend() *FakeBackend {
+	return &FakeBackend{
+		VMs:    make(map[int]*FakeVM),
+		Errors: make(map[string]error),
+	}
+}
+
+// record 记录一次调用并返回注入的错误
+func (f *FakeBackend) record(op string, args ...interface{}) error {
+	parts := []string{op}
+	for _, a := range args {
+		parts = append(parts, fmt.Sprint(a))
+	}
+	f.Calls = append(f.Calls, strings.Join(parts, " "))
+	return f.Errors[op]
+}
+
+// assignMAC 为未指定 MAC 的 net0 生成固定 MAC，模拟 Proxmox 的行为
+func assignMAC(vmID int, opts VMOptions) {
+	net0, ok := opts["net0"]
+	if !ok || parseMAC(net0) != "" {
+		return
+	}
+	model, rest, _ := strings.Cut(net0, ",")
+	opts["net0"] = fmt.Sprintf("%s=BC:24:11:00:%02X:%02X,%s", model, (vmID>>8)&0xff, vmID&0xff, rest)
+}
+
+func (f *FakeBackend) vm(vmID int) (*FakeVM, error) {
+	vm, ok := f.VMs[vmID]
+	if !ok {
+		return nil, ErrVMNotFound
+	}
+	return vm, nil
+}
+
+func (f *FakeBackend) CreateVM(vmID int, opts VMOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("create", vmID, optionArgs(opts)); err != nil {
+		return err
+	}
+	if _, ok := f.VMs[vmID]; ok {
+		return fmt.Errorf("VM %d 已存在", vmID)
+	}
+
+	vm := &FakeVM{Name: opts["name"], Status: "stopped", Options: VMOptions{}}
+	for k, v := range opts {
+		vm.Options[k] = v
+	}
+	assignMAC(vmID, vm.Options)
+	f.VMs[vmID] = vm
+	return nil
+}
+
+func (f *FakeBackend) CloneVM(srcID, dstID int, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("clone", srcID, dstID, name); err != nil {
+		return err
+	}
+	src, err := f.vm(srcID)
+	if err != nil {
+		return err
+	}
+	if _, ok := f.VMs[dstID]; ok {
+		return fmt.Errorf("VM %d 已存在", dstID)
+	}
+
+	vm := &FakeVM{Name: name, Status: "stopped", Disk: src.Disk, Options: VMOptions{}}
+	for k, v := range src.Options {
+		vm.Options[k] = v
+	}
+	vm.Options["name"] = name
+	// 克隆时重新生成 MAC
+	if net0, ok := vm.Options["net0"]; ok {
+		model, rest, _ := strings.Cut(net0, ",")
+		model, _, _ = strings.Cut(model, "=")
+		vm.Options["net0"] = model + "," + rest
+		assignMAC(dstID, vm.Options)
+	}
+	f.VMs[dstID] = vm
+	return nil
+}
+
+func (f *FakeBackend) SetVMOptions(vmID int, opts VMOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("set", vmID, optionArgs(opts)); err != nil {
+		return err
+	}
+	vm, err := f.vm(vmID)
+	if err != nil {
+		return err
+	}
+	for k, v := range opts {
+		vm.Options[k] = v
+	}
+	if name, ok := opts["name"]; ok {
+		vm.Name = name
+	}
+	return nil
+}
+
+func (f *FakeBackend) ResizeDisk(vmID int, disk, size string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("resize", vmID, disk, size); err != nil {
+		return err
+	}
+	vm, err := f.vm(vmID)
+	if err != nil {
+		return err
+	}
+	bytes, err := config.ParseSize(size)
+	if err != nil {
+		return err
+	}
+	if bytes < vm.Disk {
+		return fmt.Errorf("磁盘不能缩小")
+	}
+	vm.Disk = bytes
+	return nil
+}
+
+func (f *FakeBackend) StartVM(vmID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("start", vmID); err != nil {
+		return err
+	}
+	vm, err := f.vm(vmID)
+	if err != nil {
+		return err
+	}
+	if vm.Template {
+		return fmt.Errorf("VM %d 是模板，无法启动", vmID)
+	}
+	vm.Status = "running"
+	return nil
+}
+
+func (f *FakeBackend) StopVM(vmID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("stop", vmID); err != nil {
+		return err
+	}
+	vm, err := f.vm(vmID)
+	if err != nil {
+		return err
+	}
+	vm.Status = "stopped"
+	return nil
+}
+
+func (f *FakeBackend) ShutdownVM(vmID int, timeout time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("shutdown", vmID, timeout); err != nil {
+		return err
+	}
+	vm, err := f.vm(vmID)
+	if err != nil {
+		return err
+	}
+	vm.Status = "stopped"
+	return nil
+}
+
+func (f *FakeBackend) DestroyVM(vmID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("destroy", vmID); err != nil {
+		return err
+	}
+	if _, err := f.vm(vmID); err != nil {
+		return err
+	}
+	delete(f.VMs, vmID)
+	return nil
+}
+
+func (f *FakeBackend) VMStatus(vmID int) (*VMStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("status", vmID); err != nil {
+		return nil, err
+	}
+	vm, err := f.vm(vmID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &VMStatus{VMID: vmID, Name: vm.Name, Status: vm.Status, MaxDisk: vm.Disk, Tags: vm.Options["tags"]}
+	status.CPUs, _ = strconv.Atoi(vm.Options["cores"])
+	if mem, err := strconv.ParseInt(vm.Options["memory"], 10, 64); err == nil {
+		status.MaxMem = mem * 1024 * 1024
+	}
+	if vm.Template {
+		status.Template = 1
+	}
+	return status, nil
+}
+
+func (f *FakeBackend) ListVMs() ([]VMStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("list"); err != nil {
+		return nil, err
+	}
+
+	vms := make([]VMStatus, 0, len(f.VMs))
+	for id, vm := range f.VMs {
+		status := VMStatus{VMID: id, Name: vm.Name, Status: vm.Status, Tags: vm.Options["tags"]}
+		if vm.Template {
+			status.Template = 1
+		}
+		vms = append(vms, status)
+	}
+	sort.Slice(vms, func(i, j int) bool { return vms[i].VMID < vms[j].VMID })
+	return vms, nil
+}
+
+func (f *FakeBackend) VMConfig(vmID int) (map[string]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("config", vmID); err != nil {
+		return nil, err
+	}
+	vm, err := f.vm(vmID)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := make(map[string]string, len(vm.Options))
+	for k, v := range vm.Options {
+		cfg[k] = v
+	}
+	return cfg, nil
+}
+
+func (f *FakeBackend) ImportDisk(vmID int, disk, imagePath, storage, diskOpts string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("importdisk", vmID, disk, imagePath, storage); err != nil {
+		return err
+	}
+	vm, err := f.vm(vmID)
+	if err != nil {
+		return err
+	}
+
+	spec := fmt.Sprintf("%s:vm-%d-disk-1", storage, vmID)
+	if diskOpts != "" {
+		spec += "," + diskOpts
+	}
+	vm.Options[disk] = spec
+	vm.Disk = 1 << 30
+	return nil
+}
+
+func (f *FakeBackend) ConvertToTemplate(vmID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("template", vmID); err != nil {
+		return err
+	}
+	vm, err := f.vm(vmID)
+	if err != nil {
+		return err
+	}
+	if vm.Status == "running" {
+		return fmt.Errorf("VM %d 正在运行，无法转换为模板", vmID)
+	}
+	vm.Template = true
+	return nil
+}
+
+func (f *FakeBackend) NodeName() (string, error) {
+	return "pve", nil
+}
+
+func (f *FakeBackend) CreateSnapshot(vmID int, name, description string, vmstate bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("snapshot", vmID, name); err != nil {
+		return err
+	}
+	vm, err := f.vm(vmID)
+	if err != nil {
+		return err
+	}
+	for _, snap := range vm.Snapshots {
+		if snap.Name == name {
+			return fmt.Errorf("快照 %s 已存在", name)
+		}
+	}
+
+	snap := FakeSnapshot{
+		Snapshot: Snapshot{Name: name, Description: description, SnapTime: time.Now().Unix()},
+		Status:   "stopped",
+		Disk:     vm.Disk,
+		Options:  VMOptions{},
+	}
+	if vmstate {
+		snap.VMState = 1
+		snap.Status = vm.Status
+	}
+	for k, v := range vm.Options {
+		snap.Options[k] = v
+	}
+	vm.Snapshots = append(vm.Snapshots, snap)
+	return nil
+}
+
+func (f *FakeBackend) ListSnapshots(vmID int) ([]Snapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("listsnapshot", vmID); err != nil {
+		return nil, err
+	}
+	vm, err := f.vm(vmID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]Snapshot, len(vm.Snapshots))
+	for i, snap := range vm.Snapshots {
+		snapshots[i] = snap.Snapshot
+	}
+	return snapshots, nil
+}
+
+func (f *FakeBackend) RollbackSnapshot(vmID int, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("rollback", vmID, name); err != nil {
+		return err
+	}
+	vm, err := f.vm(vmID)
+	if err != nil {
+		return err
+	}
+	for _, snap := range vm.Snapshots {
+		if snap.Name != name {
+			continue
+		}
+		vm.Status = snap.Status
+		vm.Disk = snap.Disk
+		vm.Options = VMOptions{}
+		for k, v := range snap.Options {
+			vm.Options[k] = v
+		}
+		return nil
+	}
+	return fmt.Errorf("快照 %s 不存在", name)
+}
+
+func (f *FakeBackend) DeleteSnapshot(vmID int, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.record("delsnapshot", vmID, name); err != nil {
+		return err
+	}
+	vm, err := f.vm(vmID)
+	if err != nil {
+		return err
+	}
+	for i, snap := range vm.Snapshots {
+		if snap.Name == name {
+			vm.Snapshots = append(vm.Snapshots[:i], vm.Snapshots[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("快照 %s 不存在", name)
+}
+
+// VMIDs 返回当前所有虚拟机 ID（升序）
+func (f *FakeBackend) VMIDs() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ids := make([]int, 0, len(f.VMs))
+	for id := range f.VMs {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
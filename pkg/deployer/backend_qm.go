@@ -0,0 +1,305 @@
+package deployer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// QMBackend 通过本机 qm 命令操作 Proxmox，必须在 Proxmox VE 主机上运行
+type QMBackend struct {
+	config config.ProxmoxConfig
+}
+
+// NewQMBackend 创建 qm 命令行后端
+func NewQMBackend(cfg config.ProxmoxConfig) *QMBackend {
+	return &QMBackend{config: cfg}
+}
+
+// getProxmoxEnv 返回配置了 Proxmox 认证的环境变量
+func (b *QMBackend) getProxmoxEnv() []string {
+	env := os.Environ()
+
+	// 根据配置的认证方式选择
+	switch b.config.AuthMethod {
+	case "api_token":
+		// 使用 API Token 认证
+		if b.config.APITokenID != "" && b.config.APIToken != "" {
+			env = append(env, fmt.Sprintf("PROXMOX_TOKEN_ID=%s", b.config.APITokenID))
+			env = append(env, fmt.Sprintf("PROXMOX_TOKEN_SECRET=%s", b.config.APIToken))
+		} else {
+			fmt.Println("⚠️  警告: auth_method 设置为 api_token，但未配置 api_token_id 或 api_token")
+		}
+	case "password":
+		// 使用密码认证
+		if b.config.Password != "" {
+			env = append(env, fmt.Sprintf("PROXMOX_PASSWORD=%s", b.config.Password))
+		} else {
+			fmt.Println("⚠️  警告: auth_method 设置为 password，但未配置 password")
+		}
+	default:
+		// 兼容旧配置：如果没有指定 auth_method，优先使用 API Token
+		if b.config.APITokenID != "" && b.config.APIToken != "" {
+			env = append(env, fmt.Sprintf("PROXMOX_TOKEN_ID=%s", b.config.APITokenID))
+			env = append(env, fmt.Sprintf("PROXMOX_TOKEN_SECRET=%s", b.config.APIToken))
+		} else if b.config.Password != "" {
+			env = append(env, fmt.Sprintf("PROXMOX_PASSWORD=%s", b.config.Password))
+		}
+	}
+
+	// Proxmox 主机和用户
+	env = append(env, fmt.Sprintf("PROXMOX_HOST=%s", b.config.Host))
+	env = append(env, fmt.Sprintf("PROXMOX_USER=%s", b.config.User))
+
+	// TLS 验证
+	if b.config.SkipTLSVerify {
+		env = append(env, "PROXMOX_SKIP_TLS_VERIFY=1")
+	}
+
+	return env
+}
+
+// exec 执行带认证的 qm 命令
+// 输出不直接打印，避免并发操作多个节点时交错，失败时附在错误中返回
+func (b *QMBackend) exec(args ...string) error {
+	cmd := exec.Command("qm", args...)
+	cmd.Env = b.getProxmoxEnv()
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// output 执行 qm 命令并返回输出
+func (b *QMBackend) output(args ...string) (string, error) {
+	return b.run("qm", args...)
+}
+
+// run 执行带认证的命令并返回输出，"不存在"错误转换为 ErrVMNotFound
+func (b *QMBackend) run(name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Env = b.getProxmoxEnv()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "does not exist") {
+			return "", ErrVMNotFound
+		}
+		if msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// optionArgs 将选项转换为 qm 参数，按键名排序保证顺序稳定
+func optionArgs(opts VMOptions) []string {
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var args []string
+	for _, k := range keys {
+		args = append(args, "--"+k, opts[k])
+	}
+	return args
+}
+
+// parseKeyValues 解析 qm 输出的 "key: value" 行
+func parseKeyValues(out string) map[string]string {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+func (b *QMBackend) CreateVM(vmID int, opts VMOptions) error {
+	args := append([]string{"create", strconv.Itoa(vmID)}, optionArgs(opts)...)
+	return b.exec(args...)
+}
+
+func (b *QMBackend) CloneVM(srcID, dstID int, name string) error {
+	return b.exec("clone", strconv.Itoa(srcID), strconv.Itoa(dstID), "--name", name, "--full", "1")
+}
+
+func (b *QMBackend) SetVMOptions(vmID int, opts VMOptions) error {
+	args := append([]string{"set", strconv.Itoa(vmID)}, optionArgs(opts)...)
+	return b.exec(args...)
+}
+
+func (b *QMBackend) ResizeDisk(vmID int, disk, size string) error {
+	return b.exec("resize", strconv.Itoa(vmID), disk, size)
+}
+
+func (b *QMBackend) StartVM(vmID int) error {
+	return b.exec("start", strconv.Itoa(vmID))
+}
+
+func (b *QMBackend) StopVM(vmID int) error {
+	return b.exec("stop", strconv.Itoa(vmID))
+}
+
+func (b *QMBackend) ShutdownVM(vmID int, timeout time.Duration) error {
+	return b.exec("shutdown", strconv.Itoa(vmID),
+		"--timeout", strconv.Itoa(int(timeout.Seconds())),
+		"--forceStop", "1",
+	)
+}
+
+func (b *QMBackend) DestroyVM(vmID int) error {
+	return b.exec("destroy", strconv.Itoa(vmID), "--purge")
+}
+
+func (b *QMBackend) VMStatus(vmID int) (*VMStatus, error) {
+	out, err := b.output("status", strconv.Itoa(vmID), "--verbose")
+	if err != nil {
+		return nil, err
+	}
+
+	values := parseKeyValues(out)
+	status := &VMStatus{
+		VMID:   vmID,
+		Name:   values["name"],
+		Status: values["status"],
+		Tags:   values["tags"],
+	}
+	status.CPUs, _ = strconv.Atoi(values["cpus"])
+	status.CPU, _ = strconv.ParseFloat(values["cpu"], 64)
+	status.MaxMem, _ = strconv.ParseInt(values["maxmem"], 10, 64)
+	status.Mem, _ = strconv.ParseInt(values["mem"], 10, 64)
+	status.MaxDisk, _ = strconv.ParseInt(values["maxdisk"], 10, 64)
+	status.Uptime, _ = strconv.ParseInt(values["uptime"], 10, 64)
+	status.Template, _ = strconv.Atoi(values["template"])
+	return status, nil
+}
+
+func (b *QMBackend) VMConfig(vmID int) (map[string]string, error) {
+	out, err := b.output("config", strconv.Itoa(vmID))
+	if err != nil {
+		return nil, err
+	}
+	return parseKeyValues(out), nil
+}
+
+// ListVMs 通过 pvesh 读取节点上的虚拟机列表，qm list 的输出不含标签和模板标记
+func (b *QMBackend) ListVMs() ([]VMStatus, error) {
+	node, err := b.NodeName()
+	if err != nil {
+		return nil, err
+	}
+	out, err := b.run("pvesh", "get", "/nodes/"+node+"/qemu", "--output-format", "json")
+	if err != nil {
+		return nil, err
+	}
+	var vms []VMStatus
+	if err := json.Unmarshal([]byte(out), &vms); err != nil {
+		return nil, fmt.Errorf("解析虚拟机列表失败: %w", err)
+	}
+	sort.Slice(vms, func(i, j int) bool { return vms[i].VMID < vms[j].VMID })
+	return vms, nil
+}
+
+func (b *QMBackend) ImportDisk(vmID int, disk, imagePath, storage, diskOpts string) error {
+	if err := b.exec("importdisk", strconv.Itoa(vmID), imagePath, storage, "--format", "qcow2"); err != nil {
+		return fmt.Errorf("导入磁盘失败: %w", err)
+	}
+
+	// importdisk 将磁盘挂为 unusedN，找到它再挂载到目标位置
+	cfg, err := b.VMConfig(vmID)
+	if err != nil {
+		return err
+	}
+	var volume string
+	for i := 0; i < 256; i++ {
+		if v, ok := cfg[fmt.Sprintf("unused%d", i)]; ok {
+			volume = v
+		}
+	}
+	if volume == "" {
+		return fmt.Errorf("未找到导入的磁盘")
+	}
+
+	spec := volume
+	if diskOpts != "" {
+		spec += "," + diskOpts
+	}
+	return b.SetVMOptions(vmID, VMOptions{disk: spec})
+}
+
+func (b *QMBackend) ConvertToTemplate(vmID int) error {
+	return b.exec("template", strconv.Itoa(vmID))
+}
+
+func (b *QMBackend) NodeName() (string, error) {
+	// qm 只能操作本机，Proxmox 节点名即主机名
+	return os.Hostname()
+}
+
+func (b *QMBackend) CreateSnapshot(vmID int, name, description string, vmstate bool) error {
+	args := []string{"snapshot", strconv.Itoa(vmID), name}
+	if description != "" {
+		args = append(args, "--description", description)
+	}
+	if vmstate {
+		args = append(args, "--vmstate", "1")
+	}
+	return b.exec(args...)
+}
+
+func (b *QMBackend) ListSnapshots(vmID int) ([]Snapshot, error) {
+	node, err := b.NodeName()
+	if err != nil {
+		return nil, err
+	}
+
+	// qm listsnapshot 只输出树形文本，通过 pvesh 获取 JSON
+	out, err := b.run("pvesh", "get", fmt.Sprintf("/nodes/%s/qemu/%d/snapshot", node, vmID), "--output-format", "json")
+	if err != nil {
+		return nil, err
+	}
+	var all []Snapshot
+	if err := json.Unmarshal([]byte(out), &all); err != nil {
+		return nil, fmt.Errorf("解析快照列表失败: %w", err)
+	}
+
+	snapshots := make([]Snapshot, 0, len(all))
+	for _, s := range all {
+		if s.Name != "current" {
+			snapshots = append(snapshots, s)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].SnapTime < snapshots[j].SnapTime })
+	return snapshots, nil
+}
+
+func (b *QMBackend) RollbackSnapshot(vmID int, name string) error {
+	return b.exec("rollback", strconv.Itoa(vmID), name)
+}
+
+func (b *QMBackend) DeleteSnapshot(vmID int, name string) error {
+	return b.exec("delsnapshot", strconv.Itoa(vmID), name)
+}
@@ -1,74 +1,46 @@
 package deployer
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
-	"time"
+	"sync"
+
+	"gopkg.in/yaml.v3"
 
 	"talos-proxmox-deployer/pkg/config"
+	"talos-proxmox-deployer/pkg/state"
 )
 
 type Deployer struct {
-	config *config.ClusterConfig
-}
+	config  *config.ClusterConfig
+	backend ProxmoxBackend
 
-// getProxmoxEnv 返回配置了 Proxmox 认证的环境变量
-func (d *Deployer) getProxmoxEnv() []string {
-	env := os.Environ()
+	stateMu sync.Mutex
+	state   *state.State
 
-	// 根据配置的认证方式选择
-	switch d.config.Proxmox.AuthMethod {
-	case "api_token":
-		// 使用 API Token 认证
-		if d.config.Proxmox.APITokenID != "" && d.config.Proxmox.APIToken != "" {
-			env = append(env, fmt.Sprintf("PROXMOX_TOKEN_ID=%s", d.config.Proxmox.APITokenID))
-			env = append(env, fmt.Sprintf("PROXMOX_TOKEN_SECRET=%s", d.config.Proxmox.APIToken))
-		} else {
-			fmt.Println("⚠️  警告: auth_method 设置为 api_token，但未配置 api_token_id 或 api_token")
-		}
-	case "password":
-		// 使用密码认证
-		if d.config.Proxmox.Password != "" {
-			env = append(env, fmt.Sprintf("PROXMOX_PASSWORD=%s", d.config.Proxmox.Password))
-		} else {
-			fmt.Println("⚠️  警告: auth_method 设置为 password，但未配置 password")
-		}
-	default:
-		// 兼容旧配置：如果没有指定 auth_method，优先使用 API Token
-		if d.config.Proxmox.APITokenID != "" && d.config.Proxmox.APIToken != "" {
-			env = append(env, fmt.Sprintf("PROXMOX_TOKEN_ID=%s", d.config.Proxmox.APITokenID))
-			env = append(env, fmt.Sprintf("PROXMOX_TOKEN_SECRET=%s", d.config.Proxmox.APIToken))
-		} else if d.config.Proxmox.Password != "" {
-			env = append(env, fmt.Sprintf("PROXMOX_PASSWORD=%s", d.config.Proxmox.Password))
-		}
-	}
-
-	// Proxmox 主机和用户
-	env = append(env, fmt.Sprintf("PROXMOX_HOST=%s", d.config.Proxmox.Host))
-	env = append(env, fmt.Sprintf("PROXMOX_USER=%s", d.config.Proxmox.User))
-
-	// TLS 验证
-	if d.config.Proxmox.SkipTLSVerify {
-		env = append(env, "PROXMOX_SKIP_TLS_VERIFY=1")
-	}
+	// resume 为 true 时跳过已记录完成的节点
+	resume bool
+	// parallel 同时处理的节点数，0 表示使用配置文件中的设置
+	parallel int
 
-	return env
+	// command 构建 talosctl 和 kubectl 命令，测试中替换为假命令
+	command func(name string, args ...string) *exec.Cmd
 }
 
-// execProxmoxCommand 执行带认证的 Proxmox 命令
-func (d *Deployer) execProxmoxCommand(name string, args ...string) error {
-	cmd := exec.Command(name, args...)
-	cmd.Env = d.getProxmoxEnv()
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+func New(cfg *config.ClusterConfig, backend ProxmoxBackend) *Deployer {
+	return &Deployer{config: cfg, backend: backend, command: exec.Command}
 }
 
-func New(cfg *config.ClusterConfig) *Deployer {
-	return &Deployer{config: cfg}
+// templateName 模板虚拟机的名称
+const templateName = "talos-template"
+
+// imageFile 返回下载的 Talos 镜像文件路径
+func (d *Deployer) imageFile() string {
+	return fmt.Sprintf("talos-%s.qcow2", d.config.TalosVersion)
 }
 
 // getProxyEnv 返回配置了代理的环境变量
@@ -96,7 +68,7 @@ func (d *Deployer) getProxyEnv() []string {
 func (d *Deployer) PrepareImage() error {
 	fmt.Println("📦 准备 Talos 镜像...")
 
-	imageFile := fmt.Sprintf("talos-%s.qcow2", d.config.TalosVersion)
+	imageFile := d.imageFile()
 	if _, err := os.Stat(imageFile); err == nil {
 		fmt.Printf("✓ 镜像已存在: %s\n", imageFile)
 		return nil
@@ -171,59 +143,63 @@ func (d *Deployer) CreateTemplate() error {
 	vmID := d.config.Proxmox.TemplateVMID
 
 	// 检查模板是否存在
-	cmd := exec.Command("qm", "status", fmt.Sprintf("%d", vmID))
-	cmd.Env = d.getProxmoxEnv()
-	if cmd.Run() == nil {
+	status, err := d.backend.VMStatus(vmID)
+	switch {
+	case err == nil && status.Template == 1:
 		fmt.Printf("✓ 模板已存在 (VM ID: %d)\n", vmID)
-		return nil
+		return d.recordTemplate()
+	case err == nil && status.Name != templateName:
+		return fmt.Errorf("VM ID %d 已被其他虚拟机 %q 占用", vmID, status.Name)
+	case err == nil:
+		// 上次创建中断，继续完成剩余步骤
+		fmt.Printf("  继续创建未完成的模板 (VM ID: %d)\n", vmID)
+	case errors.Is(err, ErrVMNotFound):
+		// 创建虚拟机
+		if err := d.backend.CreateVM(vmID, VMOptions{
+			"name":     templateName,
+			"memory":   "1024",
+			"cores":    "1",
+			"cpu":      "host",
+			"net0":     fmt.Sprintf("virtio,bridge=%s", d.config.Network.Bridge),
+			"scsihw":   "virtio-scsi-pci",
+			"machine":  "q35",
+			"bios":     "ovmf",
+			"efidisk0": fmt.Sprintf("%s:4,format=qcow2", d.config.Proxmox.StoragePool),
+			"agent":    "enabled=1",
+		}); err != nil {
+			return fmt.Errorf("创建虚拟机失败: %w", err)
+		}
+	default:
+		return fmt.Errorf("检查模板状态失败: %w", err)
 	}
 
-	// 创建虚拟机
-	args := []string{
-		"create", fmt.Sprintf("%d", vmID),
-		"--name", "talos-template",
-		"--memory", "1024",
-		"--cores", "1",
-		"--cpu", "host",
-		"--net0", fmt.Sprintf("virtio,bridge=%s", d.config.Network.Bridge),
-		"--scsihw", "virtio-scsi-pci",
-		"--machine", "q35",
-		"--bios", "ovmf",
-		"--efidisk0", fmt.Sprintf("%s:4,format=qcow2", d.config.Proxmox.StoragePool),
-		"--agent", "enabled=1",
-	}
-	if err := d.execProxmoxCommand("qm", args...); err != nil {
-		return fmt.Errorf("创建虚拟机失败: %w", err)
-	}
-
-	// 导入磁盘
-	imageFile := fmt.Sprintf("talos-%s.qcow2", d.config.TalosVersion)
-	if err := d.execProxmoxCommand("qm", "importdisk",
-		fmt.Sprintf("%d", vmID),
-		imageFile,
-		d.config.Proxmox.StoragePool,
-		"--format", "qcow2",
-	); err != nil {
-		return fmt.Errorf("导入磁盘失败: %w", err)
+	// 导入并附加磁盘
+	vmCfg, err := d.backend.VMConfig(vmID)
+	if err != nil {
+		return fmt.Errorf("读取模板配置失败: %w", err)
 	}
-
-	// 附加磁盘
-	diskSpec := fmt.Sprintf("%s:vm-%d-disk-0,discard=on,cache=writeback,iothread=1,ssd=1",
-		d.config.Proxmox.StoragePool, vmID)
-	if err := d.execProxmoxCommand("qm", "set", fmt.Sprintf("%d", vmID), "--scsi0", diskSpec); err != nil {
-		return fmt.Errorf("附加磁盘失败: %w", err)
+	if _, ok := vmCfg["scsi0"]; !ok {
+		imageFile := d.imageFile()
+		if err := d.backend.ImportDisk(vmID, "scsi0", imageFile, d.config.Proxmox.StoragePool,
+			"discard=on,cache=writeback,iothread=1,ssd=1"); err != nil {
+			return fmt.Errorf("导入磁盘失败: %w", err)
+		}
 	}
 
 	// 设置启动顺序
-	if err := d.execProxmoxCommand("qm", "set", fmt.Sprintf("%d", vmID), "--boot", "order=scsi0"); err != nil {
+	if err := d.backend.SetVMOptions(vmID, VMOptions{"boot": "order=scsi0"}); err != nil {
 		return fmt.Errorf("设置启动顺序失败: %w", err)
 	}
 
 	// 转换为模板
-	if err := d.execProxmoxCommand("qm", "template", fmt.Sprintf("%d", vmID)); err != nil {
+	if err := d.backend.ConvertToTemplate(vmID); err != nil {
 		return fmt.Errorf("转换模板失败: %w", err)
 	}
 
+	if err := d.recordTemplate(); err != nil {
+		return err
+	}
+
 	fmt.Printf("✓ 模板创建完成 (VM ID: %d)\n", vmID)
 	return nil
 }
@@ -231,70 +207,128 @@ func (d *Deployer) CreateTemplate() error {
 func (d *Deployer) CreateNodes() error {
 	fmt.Println("🖥️  创建集群节点...")
 
-	allNodes := append(d.config.Nodes.ControlPlanes, d.config.Nodes.Workers...)
+	if err := d.checkVMIDChanges(); err != nil {
+		return err
+	}
 
-	for _, node := range allNodes {
-		if err := d.createNode(node); err != nil {
+	// 控制平面全部就绪后再创建工作节点
+	err := d.forEachNodeByRole(d.config.AllNodes(), func(node config.NodeSpec, log nodeLogger) error {
+		if d.nodeDone(node.Name, node.VMID, func(n state.NodeState) bool { return n.Created }) {
+			log.Printf("  ✓ 节点 %s 已创建，跳过\n", node.Name)
+			return nil
+		}
+		if err := d.createNode(node, log); err != nil {
 			return fmt.Errorf("创建节点 %s 失败: %w", node.Name, err)
 		}
+		return d.recordNodeCreated(node)
+	})
+	if err != nil {
+		return err
 	}
 
 	fmt.Println("✓ 所有节点创建完成")
 	return nil
 }
 
-func (d *Deployer) createNode(node config.NodeSpec) error {
-	fmt.Printf("  创建节点: %s (VM ID: %d)\n", node.Name, node.VMID)
+// checkVMIDChanges 检查配置中修改了 VM ID 的节点，部署状态记录的旧虚拟机仍存在时拒绝继续，
+// 否则会克隆出 IP 相同的第二台虚拟机，并且旧虚拟机从部署状态中消失，destroy 再也找不到它
+func (d *Deployer) checkVMIDChanges() error {
+	s, err := d.State()
+	if err != nil {
+		return err
+	}
+	for _, node := range d.config.AllNodes() {
+		recorded, ok := s.Lookup(node.Name)
+		if !ok || recorded.VMID == 0 || recorded.VMID == node.VMID {
+			continue
+		}
+		_, err := d.backend.VMStatus(recorded.VMID)
+		if errors.Is(err, ErrVMNotFound) {
+			// 旧虚拟机已不存在，按新的 VM ID 创建
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("检查节点 %s 原虚拟机 (VM ID: %d) 失败: %w", node.Name, recorded.VMID, err)
+		}
+		return fmt.Errorf("节点 %s 的 VM ID 在配置中改为 %d，但部署状态记录的虚拟机 %d 仍存在；"+
+			"请将配置改回 %d，或先运行 destroy --node %s 删除原虚拟机",
+			node.Name, node.VMID, recorded.VMID, recorded.VMID, node.Name)
+	}
+	return nil
+}
+
+func (d *Deployer) createNode(node config.NodeSpec, log nodeLogger) error {
+	log.Printf("  创建节点: %s (VM ID: %d)\n", node.Name, node.VMID)
+
+	// 虚拟机已存在时只做必要的调整
+	status, err := d.backend.VMStatus(node.VMID)
+	if err == nil {
+		if err := d.reconcileNode(node, status, log); err != nil {
+			return err
+		}
+		return d.recordNode(node)
+	} else if !errors.Is(err, ErrVMNotFound) {
+		return fmt.Errorf("检查虚拟机状态失败: %w", err)
+	}
 
 	// 克隆模板
-	if err := d.execProxmoxCommand("qm", "clone",
-		fmt.Sprintf("%d", d.config.Proxmox.TemplateVMID),
-		fmt.Sprintf("%d", node.VMID),
-		"--name", node.Name,
-		"--full", "1",
-	); err != nil {
+	log.Printf("    克隆模板 %d\n", d.config.Proxmox.TemplateVMID)
+	if err := d.backend.CloneVM(d.config.Proxmox.TemplateVMID, node.VMID, node.Name); err != nil {
 		return fmt.Errorf("克隆失败: %w", err)
 	}
+	if err := d.recordNode(node); err != nil {
+		return err
+	}
 
-	// 配置资源
-	diskSpec := fmt.Sprintf("%s:vm-%d-disk-0,discard=on,cache=writeback,iothread=1,ssd=1,size=%s",
-		d.config.Proxmox.StoragePool, node.VMID, node.Disk)
-
-	if err := d.execProxmoxCommand("qm", "set", fmt.Sprintf("%d", node.VMID),
-		"--cores", fmt.Sprintf("%d", node.CPU),
-		"--memory", fmt.Sprintf("%d", node.Memory),
-		"--scsi0", diskSpec,
-	); err != nil {
+	// 配置资源和标签
+	if err := d.backend.SetVMOptions(node.VMID, VMOptions{
+		"cores":  fmt.Sprintf("%d", node.CPU),
+		"memory": fmt.Sprintf("%d", node.Memory),
+		"tags":   d.nodeTags(),
+	}); err != nil {
 		return fmt.Errorf("配置资源失败: %w", err)
 	}
+	if err := d.backend.ResizeDisk(node.VMID, "scsi0", node.Disk); err != nil {
+		return fmt.Errorf("调整磁盘大小失败: %w", err)
+	}
 
 	// 启动节点
-	if err := d.execProxmoxCommand("qm", "start", fmt.Sprintf("%d", node.VMID)); err != nil {
+	log.Println("    启动虚拟机")
+	if err := d.backend.StartVM(node.VMID); err != nil {
 		return fmt.Errorf("启动节点失败: %w", err)
 	}
 
-	time.Sleep(2 * time.Second)
-	return nil
+	return d.waitVMRunning(node, log)
 }
 
 func (d *Deployer) GenerateConfig() error {
 	fmt.Println("📝 生成 Talos 配置...")
 
-	configDir := fmt.Sprintf("./%s-config", d.config.ClusterName)
+	configDir := d.config.ConfigDir()
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return fmt.Errorf("创建配置目录失败: %w", err)
 	}
 
-	// 获取第一个控制平面 IP
-	controlPlaneIP := d.config.Nodes.ControlPlanes[0].IPAddress
-	endpoint := fmt.Sprintf("https://%s:6443", controlPlaneIP)
+	// 配置了 VIP 时使用 VIP，否则使用第一个控制平面 IP
+	endpoint := d.config.ControlPlaneEndpoint()
+
+	// secrets 只生成一次，之后始终基于它生成配置，保证 CA 和 token 不变
+	if err := d.ensureSecrets(); err != nil {
+		return err
+	}
 
 	// 生成基础配置
-	cmd := exec.Command("talosctl", "gen", "config",
+	args := []string{"gen", "config",
 		d.config.ClusterName,
 		endpoint,
+		"--with-secrets", d.SecretsPath(),
 		"--output", configDir,
-	)
+		"--force",
+	}
+	if d.config.KubernetesVersion != "" {
+		args = append(args, "--kubernetes-version", d.config.KubernetesVersionFull())
+	}
+	cmd := d.command("talosctl", args...)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("生成配置失败: %w", err)
 	}
@@ -306,6 +340,20 @@ func (d *Deployer) GenerateConfig() error {
 		}
 	}
 
+	// 为每个节点生成专属配置（主机名、静态 IP、网关、DNS）
+	if err := d.renderNodeConfigs(configDir); err != nil {
+		return err
+	}
+
+	// 重新生成的 talosconfig 不含端点，重新写入
+	if err := d.configureTalosEndpoints(); err != nil {
+		return err
+	}
+
+	if err := d.recordConfigHashes(); err != nil {
+		return err
+	}
+
 	fmt.Printf("✓ 配置已生成到: %s\n", configDir)
 	return nil
 }
@@ -320,8 +368,19 @@ func (d *Deployer) applyRegistryConfig(configDir string) error {
 		filepath.Join(configDir, "worker.yaml"),
 	}
 
+	// 创建临时 patch 文件
+	patch, err := d.buildRegistryPatch()
+	if err != nil {
+		return fmt.Errorf("构建镜像源 patch 失败: %w", err)
+	}
+	patchFile := filepath.Join(configDir, "registry.patch.yaml")
+	if err := os.WriteFile(patchFile, patch, 0644); err != nil {
+		return fmt.Errorf("创建 patch 文件失败: %w", err)
+	}
+	defer os.Remove(patchFile)
+
 	for _, configFile := range configFiles {
-		if err := d.patchConfigFile(configFile); err != nil {
+		if err := d.patchConfigFile(configFile, configFile, "@"+patchFile); err != nil {
 			return fmt.Errorf("修改配置文件 %s 失败: %w", configFile, err)
 		}
 	}
@@ -329,144 +388,123 @@ func (d *Deployer) applyRegistryConfig(configDir string) error {
 	return nil
 }
 
-// patchConfigFile 使用 talosctl patch 命令修改配置文件
-func (d *Deployer) patchConfigFile(configFile string) error {
-	// 构建 registry 配置的 JSON patch
-	patchContent := d.buildRegistryPatch()
-
-	// 创建临时 patch 文件
-	patchFile := configFile + ".patch.json"
-	if err := os.WriteFile(patchFile, []byte(patchContent), 0644); err != nil {
-		return fmt.Errorf("创建 patch 文件失败: %w", err)
+// patchConfigFile 使用 talosctl patch 命令将 patches 应用到 inFile 并写入 outFile
+// patch 可以是内联内容或 "@文件路径"
+func (d *Deployer) patchConfigFile(inFile, outFile string, patches ...string) error {
+	args := []string{"machineconfig", "patch", inFile}
+	for _, p := range patches {
+		args = append(args, "--patch", p)
 	}
-	defer os.Remove(patchFile)
+	args = append(args, "--output", outFile)
 
-	// 使用 talosctl patch 命令
-	cmd := exec.Command("talosctl", "machineconfig", "patch",
-		configFile,
-		"--patch", fmt.Sprintf("@%s", patchFile),
-		"--output", configFile,
-	)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("patch 配置失败: %w", err)
+	cmd := d.command("talosctl", args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("patch 配置失败: %w: %s", err, strings.TrimSpace(string(out)))
 	}
 
 	return nil
 }
 
-// buildRegistryPatch 构建镜像源配置的 JSON patch
-func (d *Deployer) buildRegistryPatch() string {
-	var mirrors []string
-
+// buildRegistryPatch 构建镜像源配置的 strategic merge patch
+func (d *Deployer) buildRegistryPatch() ([]byte, error) {
+	mirrors := make(map[string]interface{}, len(d.config.Registry.Mirrors))
 	for registry, mirror := range d.config.Registry.Mirrors {
-		endpoints := make([]string, len(mirror.Endpoints))
-		for i, ep := range mirror.Endpoints {
-			endpoints[i] = fmt.Sprintf(`"%s"`, ep)
-		}
-		endpointsStr := strings.Join(endpoints, ",")
-
-		mirrorConfig := fmt.Sprintf(`"%s":{"endpoints":[%s]}`, registry, endpointsStr)
-		mirrors = append(mirrors, mirrorConfig)
+		mirrors[registry] = map[string]interface{}{"endpoints": mirror.Endpoints}
 	}
 
-	mirrorsStr := strings.Join(mirrors, ",")
-
-	patch := fmt.Sprintf(`[
-  {
-    "op": "add",
-    "path": "/machine/registries",
-    "value": {
-      "mirrors": {%s}
-    }
-  }
-]`, mirrorsStr)
-
-	return patch
+	return yaml.Marshal(map[string]interface{}{
+		"machine": map[string]interface{}{
+			"registries": map[string]interface{}{"mirrors": mirrors},
+		},
+	})
 }
 
 func (d *Deployer) ApplyConfig() error {
 	fmt.Println("⚙️  应用 Talos 配置...")
 
-	configDir := fmt.Sprintf("./%s-config", d.config.ClusterName)
+	// 控制平面在前，工作节点在后
+	err := d.forEachNodeByRole(d.config.AllNodes(), func(node config.NodeSpec, log nodeLogger) error {
+		if d.nodeDone(node.Name, node.VMID, func(n state.NodeState) bool { return n.Configured }) {
+			log.Printf("  ✓ 节点 %s 已应用配置，跳过\n", node.Name)
+			return nil
+		}
+		return d.configureNode(node, log)
+	})
+	if err != nil {
+		return err
+	}
 
-	// 应用控制平面配置
-	for _, node := range d.config.Nodes.ControlPlanes {
-		fmt.Printf("  应用配置到控制平面: %s (%s)\n", node.Name, node.IPAddress)
+	fmt.Println("✓ 配置应用完成")
+	return nil
+}
 
-		configFile := filepath.Join(configDir, "controlplane.yaml")
-		cmd := exec.Command("talosctl", "apply-config",
-			"--insecure",
-			"--nodes", node.IPAddress,
-			"--file", configFile,
-			"--timeout", "5m",
-		)
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("应用配置到 %s 失败: %w", node.Name, err)
-		}
-		time.Sleep(5 * time.Second)
+// configureNode 等待维护模式下的节点响应，应用节点配置并等待其安装完成
+func (d *Deployer) configureNode(node config.NodeSpec, log nodeLogger) error {
+	if node.Role == "controlplane" {
+		log.Printf("  应用配置到控制平面: %s (%s)\n", node.Name, node.IPAddress)
+	} else {
+		log.Printf("  应用配置到工作节点: %s (%s)\n", node.Name, node.IPAddress)
 	}
 
-	// 应用工作节点配置
-	for _, node := range d.config.Nodes.Workers {
-		fmt.Printf("  应用配置到工作节点: %s (%s)\n", node.Name, node.IPAddress)
+	if err := d.waitTalosAPI(node, log); err != nil {
+		return fmt.Errorf("节点 %s 未就绪: %w", node.Name, err)
+	}
 
-		configFile := filepath.Join(configDir, "worker.yaml")
-		cmd := exec.Command("talosctl", "apply-config",
-			"--insecure",
-			"--nodes", node.IPAddress,
-			"--file", configFile,
-			"--timeout", "5m",
-		)
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("应用配置到 %s 失败: %w", node.Name, err)
-		}
-		time.Sleep(5 * time.Second)
+	// 已退出维护模式的节点不能再用 --insecure 应用
+	if d.isNodeConfigured(node) {
+		log.Println("    ✓ 节点已配置，跳过")
+		return d.recordNodeConfigured(node)
 	}
 
-	fmt.Println("✓ 配置应用完成")
-	return nil
+	if err := d.ApplyNodeConfig(node, true); err != nil {
+		return fmt.Errorf("应用配置到 %s 失败: %w", node.Name, err)
+	}
+	if err := d.waitConfigured(node, log); err != nil {
+		return fmt.Errorf("节点 %s 应用配置后未就绪: %w", node.Name, err)
+	}
+	return d.recordNodeConfigured(node)
 }
 
 func (d *Deployer) Bootstrap() error {
 	fmt.Println("🚀 引导 Kubernetes 集群...")
 
 	// 配置端点
-	var endpoints []string
-	for _, node := range d.config.Nodes.ControlPlanes {
-		endpoints = append(endpoints, node.IPAddress)
+	if err := d.configureTalosEndpoints(); err != nil {
+		return err
 	}
-	endpointStr := strings.Join(endpoints, ",")
 
-	firstCP := d.config.Nodes.ControlPlanes[0].IPAddress
+	firstNode := d.bootstrapNode()
+	firstCP := firstNode.IPAddress
 
-	cmd := exec.Command("talosctl", "config", "endpoint", endpointStr)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("配置端点失败: %w", err)
-	}
+	if d.isEtcdBootstrapped(firstCP) {
+		fmt.Println("✓ etcd 已引导，跳过")
+	} else {
+		// 等待第一个控制平面完成安装并退出维护模式
+		if err := d.waitConfigured(firstNode, nodeLogger{}); err != nil {
+			return fmt.Errorf("控制平面 %s 未就绪: %w", firstNode.Name, err)
+		}
 
-	cmd = exec.Command("talosctl", "config", "node", firstCP)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("配置节点失败: %w", err)
+		// 引导集群
+		cmd := d.talosctl("bootstrap",
+			"--nodes", firstCP,
+			"--timeout", "5m",
+		)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("引导失败: %w", err)
+		}
 	}
-
-	// 等待节点准备
-	fmt.Println("等待节点准备...")
-	time.Sleep(30 * time.Second)
-
-	// 引导集群
-	cmd = exec.Command("talosctl", "bootstrap",
-		"--nodes", firstCP,
-		"--timeout", "5m",
-	)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("引导失败: %w", err)
+	if err := d.waitEtcdHealthy(nodeLogger{}); err != nil {
+		return err
+	}
+	if err := d.recordBootstrapped(); err != nil {
+		return err
 	}
 
 	// 获取 kubeconfig
-	configDir := fmt.Sprintf("./%s-config", d.config.ClusterName)
+	configDir := d.config.ConfigDir()
 	kubeconfigPath := filepath.Join(configDir, "kubeconfig")
 
-	cmd = exec.Command("talosctl", "kubeconfig",
+	cmd := d.talosctl("kubeconfig",
 		kubeconfigPath,
 		"--nodes", firstCP,
 		"--force",
@@ -475,19 +513,32 @@ func (d *Deployer) Bootstrap() error {
 		return fmt.Errorf("获取 kubeconfig 失败: %w", err)
 	}
 
+	// 确保 kubeconfig 指向 VIP，而不是某个控制平面节点
+	if d.config.Network.VIP != "" {
+		cmd = d.kubectl("config", "set-cluster", d.config.ClusterName,
+			"--server", d.config.ControlPlaneEndpoint())
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("设置 kubeconfig 端点失败: %w", err)
+		}
+	}
+
+	if err := d.waitKubernetesAPI(nodeLogger{}); err != nil {
+		return err
+	}
+
 	fmt.Printf("✓ 集群引导完成\n")
 	fmt.Printf("✓ kubeconfig 已保存到: %s\n", kubeconfigPath)
 	return nil
 }
 
 func (d *Deployer) Verify() error {
-	configDir := fmt.Sprintf("./%s-config", d.config.ClusterName)
+	configDir := d.config.ConfigDir()
 	kubeconfigPath := filepath.Join(configDir, "kubeconfig")
 
 	os.Setenv("KUBECONFIG", kubeconfigPath)
 
 	fmt.Println("检查节点状态...")
-	cmd := exec.Command("kubectl", "get", "nodes", "-o", "wide")
+	cmd := d.command("kubectl", "get", "nodes", "-o", "wide")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
@@ -495,76 +546,72 @@ func (d *Deployer) Verify() error {
 	}
 
 	fmt.Println("\n检查 Pod 状态...")
-	cmd = exec.Command("kubectl", "get", "pods", "-A", "-o", "wide")
+	cmd = d.command("kubectl", "get", "pods", "-A", "-o", "wide")
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("获取 Pod 状态失败: %w", err)
 	}
 
-	fmt.Println("\n✓ 集群验证完成")
-	return nil
-}
-
-func (d *Deployer) StartNodes() error {
-	allNodes := append(d.config.Nodes.ControlPlanes, d.config.Nodes.Workers...)
+	fmt.Println("\n检查部署状态...")
+	if err := d.checkState(); err != nil {
+		return err
+	}
 
-	for _, node := range allNodes {
-		fmt.Printf("  启动节点: %s (VM ID: %d)\n", node.Name, node.VMID)
-		if err := d.execProxmoxCommand("qm", "start", fmt.Sprintf("%d", node.VMID)); err != nil {
-			fmt.Printf("  ⚠️  节点 %s 可能已在运行\n", node.Name)
+	if d.config.KubernetesVersion != "" {
+		fmt.Println("\n检查 Kubernetes 版本...")
+		mismatches, err := d.checkKubernetesVersion()
+		if err != nil {
+			return err
+		}
+		if len(mismatches) == 0 {
+			fmt.Printf("✓ 运行版本与配置一致 (%s)\n", d.config.KubernetesVersion)
+		} else {
+			fmt.Println("⚠️  运行版本与配置不一致:")
+			for _, m := range mismatches {
+				fmt.Printf("  - %s\n", m)
+			}
 		}
 	}
 
-	fmt.Println("✓ 节点启动完成")
+	fmt.Println("\n✓ 集群验证完成")
 	return nil
 }
 
-func (d *Deployer) StopNodes() error {
-	allNodes := append(d.config.Nodes.ControlPlanes, d.config.Nodes.Workers...)
+// ReapplyConfig 将节点专属配置重新应用到已配置的节点
+// names 为空时应用到所有节点
+func (d *Deployer) ReapplyConfig(names []string) error {
+	fmt.Println("⚙️  重新应用 Talos 配置...")
+
+	nodes, err := d.selectNodes(names)
+	if err != nil {
+		return err
+	}
 
-	for _, node := range allNodes {
-		fmt.Printf("  停止节点: %s (VM ID: %d)\n", node.Name, node.VMID)
-		if err := d.execProxmoxCommand("qm", "stop", fmt.Sprintf("%d", node.VMID)); err != nil {
-			fmt.Printf("  ⚠️  停止节点 %s 失败\n", node.Name)
+	for _, node := range nodes {
+		fmt.Printf("  应用配置: %s (%s)\n", node.Name, node.IPAddress)
+		if err := d.ApplyNodeConfig(node, false); err != nil {
+			return fmt.Errorf("应用配置到 %s 失败: %w", node.Name, err)
 		}
 	}
 
-	fmt.Println("✓ 节点停止完成")
+	fmt.Println("✓ 配置应用完成")
 	return nil
 }
 
-func (d *Deployer) Destroy() error {
-	allNodes := append(d.config.Nodes.ControlPlanes, d.config.Nodes.Workers...)
-
-	// 停止并删除所有节点
-	for _, node := range allNodes {
-		fmt.Printf("  销毁节点: %s (VM ID: %d)\n", node.Name, node.VMID)
-
-		cmd := exec.Command("qm", "stop", fmt.Sprintf("%d", node.VMID))
-		cmd.Env = d.getProxmoxEnv()
-		cmd.Run()
-		time.Sleep(1 * time.Second)
+// selectNodes 按名称筛选节点，names 为空时返回所有节点
+func (d *Deployer) selectNodes(names []string) ([]config.NodeSpec, error) {
+	if len(names) == 0 {
+		return d.config.AllNodes(), nil
+	}
 
-		if err := d.execProxmoxCommand("qm", "destroy", fmt.Sprintf("%d", node.VMID), "--purge"); err != nil {
-			fmt.Printf("  ⚠️  删除节点 %s 失败\n", node.Name)
+	var nodes []config.NodeSpec
+	for _, name := range names {
+		node, ok := d.config.FindNode(name)
+		if !ok {
+			return nil, fmt.Errorf("未找到节点: %s", name)
 		}
+		nodes = append(nodes, node)
 	}
-
-	// 删除模板
-	vmID := d.config.Proxmox.TemplateVMID
-	fmt.Printf("  删除模板 (VM ID: %d)\n", vmID)
-	cmd := exec.Command("qm", "destroy", fmt.Sprintf("%d", vmID), "--purge")
-	cmd.Env = d.getProxmoxEnv()
-	cmd.Run()
-
-	// 清理配置文件
-	configDir := fmt.Sprintf("./%s-config", d.config.ClusterName)
-	os.RemoveAll(configDir)
-
-	imageFile := fmt.Sprintf("talos-%s.qcow2", d.config.TalosVersion)
-	os.Remove(imageFile)
-
-	fmt.Println("✓ 清理完成")
-	return nil
+	return nodes, nil
 }
@@ -0,0 +1,862 @@
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"talos-proxmox-deployer/pkg/config"
+	"talos-proxmox-deployer/pkg/state"
+)
+
+// fakeCommands 替换 talosctl 和 kubectl，记录调用并以测试二进制自身作为假命令
+type fakeCommands struct {
+	mu    sync.Mutex
+	calls []string
+
+	// respond 返回假命令的标准输出和退出码，为空时输出为空并成功退出
+	respond func(name string, args []string) (string, int)
+}
+
+func (f *fakeCommands) command(name string, args ...string) *exec.Cmd {
+	f.mu.Lock()
+	f.calls = append(f.calls, name+" "+strings.Join(args, " "))
+	respond := f.respond
+	f.mu.Unlock()
+
+	stdout, code := "", 0
+	if respond != nil {
+		stdout, code = respond(name, args)
+	}
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=TestHelperProcess", "--", name}, args...)...)
+	cmd.Env = append(os.Environ(), "GO_WANT_HELPER_PROCESS=1",
+		"FAKE_STDOUT="+stdout, fmt.Sprintf("FAKE_EXIT=%d", code))
+	return cmd
+}
+
+// called 返回包含 substr 的调用
+func (f *fakeCommands) called(substr string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var matched []string
+	for _, c := range f.calls {
+		if strings.Contains(c, substr) {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
+
+// TestHelperProcess 不是真正的测试，由 fakeCommands 作为子进程启动
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	fmt.Print(os.Getenv("FAKE_STDOUT"))
+	code, _ := strconv.Atoi(os.Getenv("FAKE_EXIT"))
+	os.Exit(code)
+}
+
+// argValue 返回命令参数中 flag 之后的值
+func argValue(args []string, flag string) string {
+	for i, a := range args {
+		if a == flag && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// chdir 切换到临时目录，配置目录和镜像文件都相对于当前目录
+func chdir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func testConfig() *config.ClusterConfig {
+	node := func(vmID int, name, ip, role string) config.NodeSpec {
+		return config.NodeSpec{VMID: vmID, Name: name, IPAddress: ip, CPU: 2, Memory: 4096, Disk: "20G", Role: role}
+	}
+	return &config.ClusterConfig{
+		ClusterName:  "test",
+		TalosVersion: "v1.6.0",
+		Network: config.NetworkConfig{
+			Bridge:    "vmbr0",
+			DNSServer: "192.168.1.1",
+			Gateway:   "192.168.1.1",
+			Netmask:   "255.255.255.0",
+		},
+		Proxmox: config.ProxmoxConfig{StoragePool: "local-lvm", TemplateVMID: 9000},
+		Nodes: config.NodesConfig{
+			ControlPlanes: []config.NodeSpec{node(101, "talos-cp-1", "192.168.1.101", "controlplane")},
+			Workers: []config.NodeSpec{
+				node(201, "talos-worker-1", "192.168.1.201", "worker"),
+				node(202, "talos-worker-2", "192.168.1.202", "worker"),
+			},
+		},
+	}
+}
+
+// newTestDeployer 在临时目录中创建使用内存后端和假命令的 Deployer
+func newTestDeployer(t *testing.T) (*Deployer, *FakeBackend, *fakeCommands) {
+	t.Helper()
+	chdir(t)
+	backend := NewFakeBackend()
+	cmds := &fakeCommands{}
+	d := New(testConfig(), backend)
+	d.command = cmds.command
+	return d, backend, cmds
+}
+
+// deployVMs 创建模板和全部节点
+func deployVMs(t *testing.T, d *Deployer) {
+	t.Helper()
+	if err := d.CreateTemplate(); err != nil {
+		t.Fatalf("CreateTemplate: %v", err)
+	}
+	if err := d.CreateNodes(); err != nil {
+		t.Fatalf("CreateNodes: %v", err)
+	}
+}
+
+// countCalls 返回以 prefix 开头的后端调用数
+func countCalls(b *FakeBackend, prefix string) int {
+	n := 0
+	for _, c := range b.Calls {
+		if strings.HasPrefix(c, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCreateTemplate(t *testing.T) {
+	d, backend, _ := newTestDeployer(t)
+
+	if err := d.CreateTemplate(); err != nil {
+		t.Fatalf("CreateTemplate: %v", err)
+	}
+	tmpl, ok := backend.VMs[9000]
+	if !ok {
+		t.Fatal("未创建模板虚拟机 9000")
+	}
+	if !tmpl.Template || tmpl.Name != templateName {
+		t.Errorf("模板 = %+v，期望名为 %s 的模板", tmpl, templateName)
+	}
+	if !strings.HasPrefix(tmpl.Options["scsi0"], "local-lvm:") || tmpl.Options["boot"] != "order=scsi0" {
+		t.Errorf("模板磁盘或启动顺序不正确: %v", tmpl.Options)
+	}
+	s, err := d.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.TemplateVMID != 9000 {
+		t.Errorf("部署状态中的模板 = %d，期望 9000", s.TemplateVMID)
+	}
+
+	// 模板已存在时再次运行不会重复创建
+	if err := d.CreateTemplate(); err != nil {
+		t.Fatalf("再次 CreateTemplate: %v", err)
+	}
+	if n := countCalls(backend, "create "); n != 1 {
+		t.Errorf("创建虚拟机 %d 次，期望 1 次", n)
+	}
+}
+
+func TestCreateTemplateOccupied(t *testing.T) {
+	d, backend, _ := newTestDeployer(t)
+	backend.VMs[9000] = &FakeVM{Name: "db-1", Status: "running", Options: VMOptions{}}
+
+	err := d.CreateTemplate()
+	if err == nil || !strings.Contains(err.Error(), "已被其他虚拟机") {
+		t.Fatalf("CreateTemplate 错误 = %v，期望 VM ID 被占用", err)
+	}
+	if backend.VMs[9000].Template {
+		t.Error("其他虚拟机被转换为模板")
+	}
+}
+
+func TestCreateNodes(t *testing.T) {
+	d, backend, _ := newTestDeployer(t)
+	deployVMs(t, d)
+
+	s, err := d.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range d.config.AllNodes() {
+		vm, ok := backend.VMs[node.VMID]
+		if !ok {
+			t.Errorf("未创建节点 %s", node.Name)
+			continue
+		}
+		if vm.Name != node.Name || vm.Status != "running" {
+			t.Errorf("节点 %s: 名称 %q 状态 %q", node.Name, vm.Name, vm.Status)
+		}
+		if vm.Options["cores"] != "2" || vm.Options["memory"] != "4096" {
+			t.Errorf("节点 %s 的资源 = %v", node.Name, vm.Options)
+		}
+		for _, tag := range []string{"talos-cluster-test", "talos-template-9000"} {
+			if !hasTag(vm.Options["tags"], tag) {
+				t.Errorf("节点 %s 的标签 %q 缺少 %s", node.Name, vm.Options["tags"], tag)
+			}
+		}
+		if n, ok := s.Lookup(node.Name); !ok || !n.Created || n.VMID != node.VMID || n.Role != node.Role {
+			t.Errorf("节点 %s 的部署状态 = %+v", node.Name, n)
+		}
+	}
+
+	// 控制平面全部创建完成后才克隆工作节点
+	var clones []string
+	for _, c := range backend.Calls {
+		if strings.HasPrefix(c, "clone ") {
+			clones = append(clones, c)
+		}
+	}
+	if len(clones) != 3 || clones[0] != "clone 9000 101 talos-cp-1" {
+		t.Errorf("克隆顺序 = %v，期望先克隆控制平面", clones)
+	}
+
+	// 已创建的节点再次运行时跳过
+	if err := d.CreateNodes(); err != nil {
+		t.Fatalf("再次 CreateNodes: %v", err)
+	}
+	if n := countCalls(backend, "clone "); n != 3 {
+		t.Errorf("克隆 %d 次，期望 3 次", n)
+	}
+}
+
+func TestCreateNodesAdoptsExistingVM(t *testing.T) {
+	d, backend, _ := newTestDeployer(t)
+	if err := d.CreateTemplate(); err != nil {
+		t.Fatal(err)
+	}
+	// 早于标签引入创建的节点：名称一致但没有标签
+	backend.VMs[202] = &FakeVM{Name: "talos-worker-2", Status: "stopped", Disk: 20 << 30,
+		Options: VMOptions{"cores": "2", "memory": "4096", "tags": "prod"}}
+
+	if err := d.CreateNodes(); err != nil {
+		t.Fatalf("CreateNodes: %v", err)
+	}
+	if n := countCalls(backend, "clone 9000 202"); n != 0 {
+		t.Errorf("已存在的虚拟机被重新克隆 %d 次", n)
+	}
+	vm := backend.VMs[202]
+	if vm.Status != "running" {
+		t.Errorf("已停止的节点未启动: %s", vm.Status)
+	}
+	if want := "prod;talos-cluster-test;talos-template-9000"; vm.Options["tags"] != want {
+		t.Errorf("标签 = %q，期望 %q", vm.Options["tags"], want)
+	}
+}
+
+func TestCreateNodesOccupied(t *testing.T) {
+	d, backend, _ := newTestDeployer(t)
+	if err := d.CreateTemplate(); err != nil {
+		t.Fatal(err)
+	}
+	backend.VMs[201] = &FakeVM{Name: "db-1", Status: "running", Options: VMOptions{}}
+
+	err := d.CreateNodes()
+	if err == nil || !strings.Contains(err.Error(), "已被其他虚拟机") {
+		t.Fatalf("CreateNodes 错误 = %v，期望 VM ID 被占用", err)
+	}
+	if vm := backend.VMs[201]; vm.Name != "db-1" || vm.Options["tags"] != "" {
+		t.Errorf("其他虚拟机被修改: %+v", vm)
+	}
+}
+
+func TestDestroy(t *testing.T) {
+	d, backend, _ := newTestDeployer(t)
+	deployVMs(t, d)
+	if err := os.WriteFile(d.imageFile(), []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := d.PlanDestroy(DestroyOptions{})
+	if err != nil {
+		t.Fatalf("PlanDestroy: %v", err)
+	}
+	if !plan.destroysTemplate() {
+		t.Error("销毁计划未包含模板")
+	}
+	if err := d.Destroy(plan); err != nil {
+		t.Fatalf("Destroy: %v", err)
+	}
+
+	if ids := backend.VMIDs(); len(ids) != 0 {
+		t.Errorf("销毁后仍有虚拟机: %v", ids)
+	}
+	// 工作节点先于控制平面删除，模板最后删除
+	var destroyed []string
+	for _, c := range backend.Calls {
+		if strings.HasPrefix(c, "destroy ") {
+			destroyed = append(destroyed, strings.TrimPrefix(c, "destroy "))
+		}
+	}
+	if got := strings.Join(destroyed, ","); got != "201,202,101,9000" {
+		t.Errorf("删除顺序 = %s，期望 201,202,101,9000", got)
+	}
+	for _, path := range []string{d.config.ConfigDir(), d.imageFile()} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s 未删除: %v", path, err)
+		}
+	}
+}
+
+func TestDestroyFailureKeepsConfig(t *testing.T) {
+	d, backend, _ := newTestDeployer(t)
+	deployVMs(t, d)
+	backend.Errors["destroy"] = fmt.Errorf("storage locked")
+
+	plan, err := d.PlanDestroy(DestroyOptions{})
+	if err != nil {
+		t.Fatalf("PlanDestroy: %v", err)
+	}
+	if err := d.Destroy(plan); err == nil || !strings.Contains(err.Error(), "4 个虚拟机删除失败") {
+		t.Fatalf("Destroy 错误 = %v，期望报告删除失败", err)
+	}
+	if _, err := os.Stat(state.Path(d.config.ConfigDir())); err != nil {
+		t.Fatalf("删除失败后部署状态被删除: %v", err)
+	}
+
+	// 部署状态只记录仍存在的虚拟机，修复后再次运行可以完成销毁
+	delete(backend.Errors, "destroy")
+	plan, err = d.PlanDestroy(DestroyOptions{})
+	if err != nil {
+		t.Fatalf("再次 PlanDestroy: %v", err)
+	}
+	if err := d.Destroy(plan); err != nil {
+		t.Fatalf("再次 Destroy: %v", err)
+	}
+	if ids := backend.VMIDs(); len(ids) != 0 {
+		t.Errorf("销毁后仍有虚拟机: %v", ids)
+	}
+	if _, err := os.Stat(d.config.ConfigDir()); !os.IsNotExist(err) {
+		t.Errorf("全部删除后配置目录未删除: %v", err)
+	}
+}
+
+func TestDestroyPartialFailureRecordsSurvivors(t *testing.T) {
+	d, backend, _ := newTestDeployer(t)
+	deployVMs(t, d)
+	plan, err := d.PlanDestroy(DestroyOptions{KeepTemplate: true})
+	if err != nil {
+		t.Fatalf("PlanDestroy: %v", err)
+	}
+	// 只有控制平面删除失败
+	d.backend = &protectedBackend{FakeBackend: backend, protected: 101}
+	if err := d.Destroy(plan); err == nil {
+		t.Fatal("Destroy 未报告删除失败")
+	}
+
+	s, err := d.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := s.NodeNames(); len(names) != 1 || names[0] != "talos-cp-1" {
+		t.Errorf("部署状态中的节点 = %v，期望只剩删除失败的 talos-cp-1", names)
+	}
+	if _, err := os.Stat(state.Path(d.config.ConfigDir())); err != nil {
+		t.Errorf("删除失败后部署状态被删除: %v", err)
+	}
+}
+
+// protectedBackend 拒绝删除指定虚拟机，模拟开启了删除保护的虚拟机
+type protectedBackend struct {
+	*FakeBackend
+	protected int
+}
+
+func (b *protectedBackend) DestroyVM(vmID int) error {
+	if vmID == b.protected {
+		return fmt.Errorf("VM %d is protected", vmID)
+	}
+	return b.FakeBackend.DestroyVM(vmID)
+}
+
+func TestDestroyKeepsTemplateInUse(t *testing.T) {
+	tests := []struct {
+		name string
+		vm   *FakeVM
+	}{
+		{"其他集群的节点", &FakeVM{Name: "talos-cp-1", Status: "running",
+			Options: VMOptions{"tags": "talos-cluster-prod;talos-template-9000"}}},
+		{"没有模板标签的旧节点", &FakeVM{Name: "talos-old-1", Status: "running", Options: VMOptions{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, backend, _ := newTestDeployer(t)
+			deployVMs(t, d)
+			backend.VMs[300] = tt.vm
+
+			plan, err := d.PlanDestroy(DestroyOptions{KeepImage: true})
+			if err != nil {
+				t.Fatalf("PlanDestroy: %v", err)
+			}
+			if plan.destroysTemplate() || len(plan.templateUsers) != 1 {
+				t.Errorf("模板使用者 = %v，期望保留模板", plan.templateUsers)
+			}
+			if err := d.Destroy(plan); err != nil {
+				t.Fatalf("Destroy: %v", err)
+			}
+			for _, id := range []int{9000, 300} {
+				if _, ok := backend.VMs[id]; !ok {
+					t.Errorf("VM %d 被删除", id)
+				}
+			}
+			for _, node := range d.config.AllNodes() {
+				if _, ok := backend.VMs[node.VMID]; ok {
+					t.Errorf("节点 %s 未删除", node.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanDestroyOwnershipMismatch(t *testing.T) {
+	d, backend, _ := newTestDeployer(t)
+	deployVMs(t, d)
+	// 节点虚拟机被删除后，VM ID 被其他虚拟机占用
+	backend.VMs[201] = &FakeVM{Name: "db-1", Status: "running", Options: VMOptions{}}
+
+	if _, err := d.PlanDestroy(DestroyOptions{}); err == nil || !strings.Contains(err.Error(), "--ignore-ownership") {
+		t.Fatalf("PlanDestroy 错误 = %v，期望归属检查失败", err)
+	}
+	if n := countCalls(backend, "destroy "); n != 0 {
+		t.Errorf("归属检查失败后仍删除了 %d 个虚拟机", n)
+	}
+
+	// --ignore-ownership 时删除所有虚拟机
+	plan, err := d.PlanDestroy(DestroyOptions{IgnoreOwnership: true, KeepImage: true})
+	if err != nil {
+		t.Fatalf("PlanDestroy: %v", err)
+	}
+	if err := d.Destroy(plan); err != nil {
+		t.Fatalf("Destroy: %v", err)
+	}
+	if ids := backend.VMIDs(); len(ids) != 0 {
+		t.Errorf("销毁后仍有虚拟机: %v", ids)
+	}
+}
+
+func TestDestroyWorkersOnly(t *testing.T) {
+	d, backend, cmds := newTestDeployer(t)
+	deployVMs(t, d)
+
+	if _, err := d.PlanDestroy(DestroyOptions{Nodes: []string{"talos-cp-1"}}); err == nil || !strings.Contains(err.Error(), "不是工作节点") {
+		t.Fatalf("PlanDestroy 错误 = %v，期望拒绝单独销毁控制平面", err)
+	}
+
+	plan, err := d.PlanDestroy(DestroyOptions{WorkersOnly: true})
+	if err != nil {
+		t.Fatalf("PlanDestroy: %v", err)
+	}
+	if err := d.Destroy(plan); err != nil {
+		t.Fatalf("Destroy: %v", err)
+	}
+
+	for _, id := range []int{9000, 101} {
+		if _, ok := backend.VMs[id]; !ok {
+			t.Errorf("VM %d 被删除", id)
+		}
+	}
+	s, err := d.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range d.config.Nodes.Workers {
+		if _, ok := backend.VMs[node.VMID]; ok {
+			t.Errorf("工作节点 %s 未删除", node.Name)
+		}
+		if _, ok := s.Lookup(node.Name); ok {
+			t.Errorf("工作节点 %s 仍在部署状态中", node.Name)
+		}
+		if calls := cmds.called("delete node " + node.Name); len(calls) != 1 {
+			t.Errorf("kubectl delete node %s 调用 %d 次，期望 1 次", node.Name, len(calls))
+		}
+	}
+	if _, err := os.Stat(d.config.ConfigDir()); err != nil {
+		t.Errorf("只销毁工作节点时删除了配置目录: %v", err)
+	}
+}
+
+func TestCreateNodesVMIDChanged(t *testing.T) {
+	d, backend, _ := newTestDeployer(t)
+	deployVMs(t, d)
+	d.config.Nodes.Workers[0].VMID = 203
+
+	err := d.CreateNodes()
+	if err == nil || !strings.Contains(err.Error(), "destroy --node talos-worker-1") {
+		t.Fatalf("CreateNodes 错误 = %v，期望拒绝并提示先销毁原虚拟机", err)
+	}
+	if _, ok := backend.VMs[203]; ok {
+		t.Error("原虚拟机仍存在时克隆了新虚拟机")
+	}
+	s, err := d.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := s.Lookup("talos-worker-1"); n.VMID != 201 {
+		t.Errorf("部署状态中的 VM ID = %d，期望仍为 201", n.VMID)
+	}
+
+	// 销毁原虚拟机后按新的 VM ID 创建
+	plan, err := d.PlanDestroy(DestroyOptions{Nodes: []string{"talos-worker-1"}})
+	if err != nil {
+		t.Fatalf("PlanDestroy: %v", err)
+	}
+	if err := d.Destroy(plan); err != nil {
+		t.Fatalf("Destroy: %v", err)
+	}
+	if err := d.CreateNodes(); err != nil {
+		t.Fatalf("CreateNodes: %v", err)
+	}
+	if _, ok := backend.VMs[201]; ok {
+		t.Error("原虚拟机未删除")
+	}
+	if n, _ := s.Lookup("talos-worker-1"); n.VMID != 203 || !n.Created {
+		t.Errorf("部署状态 = %+v，期望记录新的 VM ID 203", n)
+	}
+}
+
+// fakeTalosVersions 模拟各节点的 Talos 版本：talosctl version 返回节点当前版本，talosctl upgrade 将其更新为镜像的版本
+type fakeTalosVersions struct {
+	mu       sync.Mutex
+	versions map[string]string // IP → 版本
+}
+
+func (v *fakeTalosVersions) respond(name string, args []string) (string, int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	ip := argValue(args, "--nodes")
+	switch {
+	case name != "talosctl" || len(args) == 0:
+	case args[len(args)-1] == "version":
+		return fmt.Sprintf("Client:\n\tTag: v1.9.0\nServer:\n\tNODE: %s\n\tTag: %s\n", ip, v.versions[ip]), 0
+	case argValue(args, "--talosconfig") != "" && contains(args, "upgrade"):
+		_, tag, _ := strings.Cut(argValue(args, "--image"), ":")
+		v.versions[ip] = tag
+	}
+	return "", 0
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestUpgradeTalos(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  string
+		target   string
+		preserve bool
+	}{
+		{"v1.8 之前保留 EPHEMERAL", "v1.6.0", "v1.7.4", true},
+		{"v1.7 升级到 v1.8", "v1.7.4", "v1.8.0", true},
+		{"v1.8 起默认保留", "v1.8.2", "v1.9.0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _, cmds := newTestDeployer(t)
+			d.config.TalosVersion = tt.target
+			versions := &fakeTalosVersions{versions: make(map[string]string)}
+			for _, node := range d.config.AllNodes() {
+				versions.versions[node.IPAddress] = tt.current
+			}
+			cmds.respond = versions.respond
+
+			if err := d.UpgradeTalos(TalosUpgradeOptions{}); err != nil {
+				t.Fatalf("UpgradeTalos: %v", err)
+			}
+
+			upgrades := cmds.called(" upgrade ")
+			// 逐个节点升级，控制平面在前
+			want := []string{"192.168.1.101", "192.168.1.201", "192.168.1.202"}
+			if len(upgrades) != len(want) {
+				t.Fatalf("升级调用 = %v，期望 %d 次", upgrades, len(want))
+			}
+			for i, ip := range want {
+				args := []string{"talosctl", "--talosconfig", d.talosconfigPath(), "upgrade",
+					"--nodes", ip, "--endpoints", ip, "--image", "ghcr.io/siderolabs/installer:" + tt.target}
+				if tt.preserve {
+					args = append(args, "--preserve")
+				}
+				if got := upgrades[i]; got != strings.Join(args, " ") {
+					t.Errorf("第 %d 次升级 = %q，期望 %q", i+1, got, strings.Join(args, " "))
+				}
+			}
+			for ip, v := range versions.versions {
+				if v != tt.target {
+					t.Errorf("节点 %s 运行 %s，期望 %s", ip, v, tt.target)
+				}
+			}
+		})
+	}
+}
+
+func TestUpgradeTalosControlPlanesFirst(t *testing.T) {
+	d, _, cmds := newTestDeployer(t)
+	d.config.TalosVersion = "v1.7.4"
+	versions := &fakeTalosVersions{versions: make(map[string]string)}
+	for _, node := range d.config.AllNodes() {
+		versions.versions[node.IPAddress] = "v1.6.0"
+	}
+	cmds.respond = versions.respond
+
+	if err := d.UpgradeTalos(TalosUpgradeOptions{Nodes: []string{"talos-worker-2", "talos-cp-1"}}); err != nil {
+		t.Fatalf("UpgradeTalos: %v", err)
+	}
+	upgrades := cmds.called(" upgrade ")
+	if len(upgrades) != 2 || !strings.Contains(upgrades[0], "--nodes 192.168.1.101 ") ||
+		!strings.Contains(upgrades[1], "--nodes 192.168.1.202 ") {
+		t.Errorf("升级顺序 = %v，期望先升级控制平面", upgrades)
+	}
+	if versions.versions["192.168.1.201"] != "v1.6.0" {
+		t.Error("升级了未选择的节点")
+	}
+}
+
+// failingStatusBackend 查询指定虚拟机状态时返回错误，模拟 Proxmox API 暂时不可用
+type failingStatusBackend struct {
+	*FakeBackend
+	failing int
+}
+
+func (b *failingStatusBackend) VMStatus(vmID int) (*VMStatus, error) {
+	if vmID == b.failing {
+		return nil, fmt.Errorf("500 Internal Server Error")
+	}
+	return b.FakeBackend.VMStatus(vmID)
+}
+
+func TestNodeStatusesVMStatusError(t *testing.T) {
+	d, backend, _ := newTestDeployer(t)
+	deployVMs(t, d)
+	d.backend = &failingStatusBackend{FakeBackend: backend, failing: 201}
+
+	statuses, err := d.NodeStatuses()
+	if err != nil {
+		t.Fatalf("NodeStatuses: %v", err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("返回 %d 个节点，期望 3 个", len(statuses))
+	}
+	for _, st := range statuses {
+		switch st.Name {
+		case "talos-worker-1":
+			if st.Power != "unknown" || st.TalosStage != "unknown" {
+				t.Errorf("%s: power %q stage %q，期望 unknown", st.Name, st.Power, st.TalosStage)
+			}
+		default:
+			if st.Power != "running" {
+				t.Errorf("%s: power %q，期望 running", st.Name, st.Power)
+			}
+		}
+	}
+}
+
+// fakeEtcd 模拟 etcd：etcd status 在不健康的节点上失败，etcd members 列出所有成员
+type fakeEtcd struct {
+	healthy map[string]bool // IP → etcd 是否健康
+	members []string        // 成员主机名
+}
+
+func (e *fakeEtcd) respond(name string, args []string) (string, int) {
+	if name != "talosctl" || len(args) < 2 || args[len(args)-2] != "etcd" {
+		return "", 0
+	}
+	ip := argValue(args, "--nodes")
+	if !e.healthy[ip] {
+		return "", 1
+	}
+	if args[len(args)-1] != "members" {
+		return "", 0
+	}
+	out := "NODE ID HOSTNAME PEER URLS CLIENT URLS LEARNER\n"
+	for i, m := range e.members {
+		out += fmt.Sprintf("%s %016x %s https://%s:2380 https://%s:2379 false\n", ip, i+1, m, m, m)
+	}
+	return out, 0
+}
+
+// withControlPlanes 将测试配置扩展为 n 个控制平面
+func withControlPlanes(d *Deployer, n int) {
+	for i := len(d.config.Nodes.ControlPlanes) + 1; i <= n; i++ {
+		d.config.Nodes.ControlPlanes = append(d.config.Nodes.ControlPlanes, config.NodeSpec{
+			VMID: 100 + i, Name: fmt.Sprintf("talos-cp-%d", i), IPAddress: fmt.Sprintf("192.168.1.%d", 100+i),
+			CPU: 2, Memory: 4096, Disk: "20G", Role: "controlplane",
+		})
+	}
+}
+
+func TestReplaceNodeRefusesToLoseQuorum(t *testing.T) {
+	tests := []struct {
+		name          string
+		controlPlanes int
+		healthy       []string // etcd 健康的控制平面 IP
+		wantErr       string
+	}{
+		{"只有一个控制平面", 1, []string{"192.168.1.101"}, "只有一个控制平面"},
+		{"没有其他健康成员", 3, []string{"192.168.1.101"}, "没有 etcd 健康的控制平面"},
+		{"剩余健康成员不足法定人数", 3, []string{"192.168.1.101", "192.168.1.102"}, "少于法定人数"},
+		{"五个成员中两个不健康", 5, []string{"192.168.1.101", "192.168.1.102", "192.168.1.103"}, "少于法定人数"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, backend, cmds := newTestDeployer(t)
+			withControlPlanes(d, tt.controlPlanes)
+			deployVMs(t, d)
+
+			etcd := &fakeEtcd{healthy: make(map[string]bool)}
+			for _, ip := range tt.healthy {
+				etcd.healthy[ip] = true
+			}
+			for _, cp := range d.config.Nodes.ControlPlanes {
+				etcd.members = append(etcd.members, cp.Name)
+			}
+			cmds.respond = etcd.respond
+
+			// 替换第一个控制平面，它本身仍健康时也不计入剩余成员
+			err := d.ReplaceNode("talos-cp-1")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ReplaceNode 错误 = %v，期望包含 %q", err, tt.wantErr)
+			}
+			if calls := cmds.called("remove-member"); len(calls) != 0 {
+				t.Errorf("拒绝替换后仍移除了 etcd 成员: %v", calls)
+			}
+			if n := countCalls(backend, "destroy ") + countCalls(backend, "stop "); n != 0 {
+				t.Errorf("拒绝替换后仍停止或删除了虚拟机 (%d 次)", n)
+			}
+			if vm := backend.VMs[101]; vm == nil || vm.Status != "running" {
+				t.Errorf("被替换节点的虚拟机状态 = %+v，期望保持运行", vm)
+			}
+		})
+	}
+}
+
+func TestReplaceNodeRemovesEtcdMemberFirst(t *testing.T) {
+	d, backend, cmds := newTestDeployer(t)
+	withControlPlanes(d, 3)
+	deployVMs(t, d)
+
+	etcd := &fakeEtcd{
+		healthy: map[string]bool{"192.168.1.102": true, "192.168.1.103": true},
+		members: []string{"talos-cp-1", "talos-cp-2", "talos-cp-3"},
+	}
+	cmds.respond = etcd.respond
+	// 克隆失败时停止，只验证移除成员和销毁虚拟机的顺序
+	backend.Errors["clone"] = fmt.Errorf("storage full")
+
+	if err := d.ReplaceNode("talos-cp-1"); err == nil {
+		t.Fatal("ReplaceNode 未返回克隆失败")
+	}
+	removes := cmds.called("remove-member")
+	want := "etcd remove-member 0000000000000001 --nodes 192.168.1.102 --endpoints 192.168.1.102"
+	if len(removes) != 1 || !strings.HasSuffix(removes[0], want) {
+		t.Errorf("移除成员调用 = %v，期望通过第一个健康节点移除 talos-cp-1", removes)
+	}
+	if _, ok := backend.VMs[101]; ok {
+		t.Error("被替换节点的虚拟机未删除")
+	}
+	for _, id := range []int{102, 103} {
+		if _, ok := backend.VMs[id]; !ok {
+			t.Errorf("VM %d 被删除", id)
+		}
+	}
+}
+
+func TestValidateSnapshotName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"a", true},
+		{"pre-upgrade-20240101-120000", true},
+		{"v1_6", true},
+		{strings.Repeat("a", 40), true},
+		{strings.Repeat("a", 41), false},
+		{"", false},
+		{"1st", false},
+		{"-a", false},
+		{"before upgrade", false},
+		{"v1.6", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateSnapshotName(tt.name); (err == nil) != tt.valid {
+			t.Errorf("ValidateSnapshotName(%q) = %v，期望有效: %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestRollbackSnapshot(t *testing.T) {
+	d, backend, _ := newTestDeployer(t)
+	deployVMs(t, d)
+	for _, node := range d.config.AllNodes() {
+		if err := backend.CreateSnapshot(node.VMID, "a", "", false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 快照之后的变更
+	backend.VMs[201].Options["memory"] = "8192"
+
+	t.Run("快照不完整时拒绝回滚", func(t *testing.T) {
+		if err := backend.CreateSnapshot(101, "partial", "", false); err != nil {
+			t.Fatal(err)
+		}
+		calls := len(backend.Calls)
+		err := d.RollbackSnapshot("partial")
+		if err == nil || !strings.Contains(err.Error(), "talos-worker-1, talos-worker-2") {
+			t.Fatalf("RollbackSnapshot 错误 = %v，期望列出缺少快照的节点", err)
+		}
+		for _, c := range backend.Calls[calls:] {
+			if strings.HasPrefix(c, "stop ") || strings.HasPrefix(c, "rollback ") {
+				t.Errorf("拒绝回滚后仍执行了 %s", c)
+			}
+		}
+	})
+
+	t.Run("回滚所有节点", func(t *testing.T) {
+		calls := len(backend.Calls)
+		if err := d.RollbackSnapshot("a"); err != nil {
+			t.Fatalf("RollbackSnapshot: %v", err)
+		}
+		if got := backend.VMs[201].Options["memory"]; got != "4096" {
+			t.Errorf("回滚后内存 = %s，期望 4096", got)
+		}
+		// 所有节点按启动的相反顺序停止后才开始回滚
+		var ops []string
+		for _, c := range backend.Calls[calls:] {
+			if strings.HasPrefix(c, "stop ") || strings.HasPrefix(c, "rollback ") {
+				ops = append(ops, c)
+			}
+		}
+		want := "stop 202,stop 201,stop 101,rollback 101 a,rollback 201 a,rollback 202 a"
+		if got := strings.Join(ops, ","); got != want {
+			t.Errorf("操作顺序 = %s，期望 %s", got, want)
+		}
+		for _, node := range d.config.AllNodes() {
+			if vm := backend.VMs[node.VMID]; vm.Status != "running" {
+				t.Errorf("节点 %s 回滚后状态 %s，期望 running", node.Name, vm.Status)
+			}
+		}
+	})
+}
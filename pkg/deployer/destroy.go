@@ -0,0 +1,471 @@
+package deployer
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"talos-proxmox-deployer/pkg/config"
+	"talos-proxmox-deployer/pkg/state"
+)
+
+// archiveTimeFormat 配置归档文件名中的时间格式
+const archiveTimeFormat = "20060102-150405"
+
+// DestroyOptions 销毁选项
+type DestroyOptions struct {
+	IgnoreOwnership bool     // 删除归属检查不通过的虚拟机
+	KeepTemplate    bool     // 保留模板
+	KeepImage       bool     // 保留下载的 Talos 镜像
+	KeepConfig      bool     // 保留配置目录（talosconfig、kubeconfig、secrets 等）
+	Archive         string   // 删除前将配置目录打包到该目录，留空不归档
+	Nodes           []string // 只销毁指定节点，留空销毁整个集群
+	WorkersOnly     bool     // 只销毁工作节点
+}
+
+// partial 判断是否只销毁部分节点
+// 只销毁部分节点时集群仍在使用，模板、镜像和配置目录都会保留
+func (o DestroyOptions) partial() bool {
+	return len(o.Nodes) > 0 || o.WorkersOnly
+}
+
+// destroyTarget 待删除的节点虚拟机
+type destroyTarget struct {
+	node     config.NodeSpec
+	exists   bool
+	running  bool
+	evidence string // 确认归属的依据
+	mismatch string // 非空表示不属于该集群
+}
+
+// DestroyPlan 销毁计划，由 PlanDestroy 生成并检查归属
+type DestroyPlan struct {
+	opts    DestroyOptions
+	targets []destroyTarget
+
+	templateVMID     int
+	templateExists   bool
+	templateMismatch string
+	templateUsers    []string // 仍从模板克隆的其他虚拟机
+}
+
+// mismatches 返回归属检查不通过的虚拟机数量
+func (p *DestroyPlan) mismatches() int {
+	n := 0
+	for _, t := range p.targets {
+		if t.mismatch != "" {
+			n++
+		}
+	}
+	if p.templateMismatch != "" {
+		n++
+	}
+	return n
+}
+
+// destroysTemplate 判断是否按计划删除模板
+func (p *DestroyPlan) destroysTemplate() bool {
+	if p.opts.partial() || p.opts.KeepTemplate || !p.templateExists || len(p.templateUsers) > 0 {
+		return false
+	}
+	return p.templateMismatch == "" || p.opts.IgnoreOwnership
+}
+
+// nodeOwnership 检查虚拟机是否属于该集群的节点，返回确认归属的依据
+// 名称必须与节点名一致，且带有集群标签或记录在部署状态中；没有部署状态的旧集群只能按名称确认
+func (d *Deployer) nodeOwnership(node config.NodeSpec, status *VMStatus, s *state.State) (string, error) {
+	if status.Template == 1 {
+		return "", fmt.Errorf("VM ID %d 是模板", node.VMID)
+	}
+	if status.Name != node.Name {
+		return "", fmt.Errorf("虚拟机名称为 %q，与节点名不符", status.Name)
+	}
+	if hasTag(status.Tags, d.clusterTag()) {
+		return "标签 " + d.clusterTag(), nil
+	}
+	if !s.Exists() {
+		return "名称（没有部署状态）", nil
+	}
+	if recorded, ok := s.Lookup(node.Name); ok && recorded.VMID == node.VMID {
+		return "部署状态", nil
+	}
+	return "", fmt.Errorf("既没有集群标签，也未记录在部署状态中")
+}
+
+// checkNodeOwnership 在删除节点虚拟机之前确认其属于该集群，依据与 destroy 相同
+func (d *Deployer) checkNodeOwnership(node config.NodeSpec, status *VMStatus) error {
+	s, err := d.State()
+	if err != nil {
+		return err
+	}
+	if _, err := d.nodeOwnership(node, status, s); err != nil {
+		return fmt.Errorf("无法确认 VM ID %d 属于节点 %s: %w", node.VMID, node.Name, err)
+	}
+	return nil
+}
+
+// PlanDestroy 检查每个待删除虚拟机的归属并打印销毁计划
+// 存在归属不匹配的虚拟机且未设置 IgnoreOwnership 时返回错误，此时不会删除任何资源
+func (d *Deployer) PlanDestroy(opts DestroyOptions) (*DestroyPlan, error) {
+	s, err := d.State()
+	if err != nil {
+		return nil, err
+	}
+
+	sel := NodeSelector{Names: opts.Nodes}
+	if opts.WorkersOnly {
+		sel.Role = "worker"
+	}
+	nodes, err := d.selectManagedNodes(sel)
+	if err != nil {
+		return nil, err
+	}
+	if opts.partial() {
+		for _, node := range nodes {
+			// 已从配置中移除的节点以部署状态记录的角色为准，角色未知时按控制平面处理
+			if node.Role != "worker" {
+				return nil, fmt.Errorf("%s 不是工作节点，单独销毁可能破坏 etcd 成员，故障节点请使用 node replace", node.Name)
+			}
+		}
+	}
+
+	plan := &DestroyPlan{opts: opts}
+	ours := make(map[int]bool)
+	for _, node := range nodes {
+		t := destroyTarget{node: node}
+		status, err := d.backend.VMStatus(node.VMID)
+		switch {
+		case err == nil:
+			t.exists = true
+			t.running = status.Status == "running"
+			if t.evidence, err = d.nodeOwnership(node, status, s); err != nil {
+				t.mismatch = err.Error()
+			} else {
+				ours[node.VMID] = true
+			}
+		case !errors.Is(err, ErrVMNotFound):
+			return nil, fmt.Errorf("检查节点 %s 的虚拟机失败: %w", node.Name, err)
+		}
+		plan.targets = append(plan.targets, t)
+	}
+
+	if !opts.partial() && !opts.KeepTemplate {
+		if err := d.planTemplateDestroy(plan, ours); err != nil {
+			return nil, err
+		}
+	}
+
+	d.printDestroyPlan(plan)
+
+	if n := plan.mismatches(); n > 0 {
+		if !opts.IgnoreOwnership {
+			return nil, fmt.Errorf("%d 个虚拟机不属于该集群，已取消销毁；确认无误后可使用 --ignore-ownership 强制删除", n)
+		}
+		fmt.Printf("⚠️  --ignore-ownership: 将删除 %d 个归属检查不通过的虚拟机\n", n)
+	}
+	return plan, nil
+}
+
+// planTemplateDestroy 检查模板归属，以及是否仍有其他集群的节点克隆自该模板
+func (d *Deployer) planTemplateDestroy(plan *DestroyPlan, ours map[int]bool) error {
+	plan.templateVMID = d.templateVMID()
+	status, err := d.backend.VMStatus(plan.templateVMID)
+	if errors.Is(err, ErrVMNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("检查模板失败: %w", err)
+	}
+	plan.templateExists = true
+
+	switch {
+	case status.Template != 1:
+		plan.templateMismatch = fmt.Sprintf("不是模板，而是虚拟机 %q", status.Name)
+		return nil
+	case status.Name != templateName:
+		plan.templateMismatch = fmt.Sprintf("模板名称为 %q，不是 %s", status.Name, templateName)
+		return nil
+	}
+
+	vms, err := d.backend.ListVMs()
+	if err != nil {
+		return fmt.Errorf("列出虚拟机失败: %w", err)
+	}
+	tag := templateTag(plan.templateVMID)
+	for _, vm := range vms {
+		if ours[vm.VMID] || vm.Template == 1 {
+			continue
+		}
+		switch {
+		case hasTag(vm.Tags, tag):
+			plan.templateUsers = append(plan.templateUsers, fmt.Sprintf("%s (%d)", vm.Name, vm.VMID))
+		case !hasTagPrefix(vm.Tags, "talos-template-") && d.looksLikeTalosNode(vm.Name):
+			// 早于模板标签部署的节点无法确认克隆来源，保守地视为仍在使用模板
+			plan.templateUsers = append(plan.templateUsers, fmt.Sprintf("%s (%d，无模板标签)", vm.Name, vm.VMID))
+		}
+	}
+	return nil
+}
+
+// looksLikeTalosNode 判断虚拟机名称是否像 Talos 节点：带有 init 默认的 talos- 前缀，
+// 或与本集群节点使用相同的命名前缀（如 k8s-node-1 与 k8s-node-2）
+func (d *Deployer) looksLikeTalosNode(name string) bool {
+	if strings.HasPrefix(name, "talos-") {
+		return true
+	}
+	m := nodeNameSuffix.FindStringSubmatch(name)
+	if m == nil || m[1] == "" {
+		return false
+	}
+	for _, node := range d.config.AllNodes() {
+		if n := nodeNameSuffix.FindStringSubmatch(node.Name); n != nil && n[1] == m[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// printDestroyPlan 打印销毁计划
+func (d *Deployer) printDestroyPlan(p *DestroyPlan) {
+	opts := p.opts
+	fmt.Println("销毁计划:")
+	if opts.Archive != "" {
+		fmt.Printf("  ✓ 归档配置目录到: %s\n", opts.Archive)
+	}
+
+	for _, t := range p.targets {
+		node := t.node
+		switch {
+		case !t.exists:
+			fmt.Printf("  - %s (VM ID: %d): 虚拟机不存在，跳过\n", node.Name, node.VMID)
+		case t.mismatch != "":
+			fmt.Printf("  ✗ %s (VM ID: %d): %s\n", node.Name, node.VMID, t.mismatch)
+		default:
+			fmt.Printf("  ✓ %s (VM ID: %d): 删除虚拟机（归属依据: %s）\n", node.Name, node.VMID, t.evidence)
+		}
+	}
+
+	if opts.partial() {
+		fmt.Println("  - 模板、镜像和配置目录: 只销毁部分节点，保留")
+		return
+	}
+
+	switch {
+	case opts.KeepTemplate:
+		fmt.Println("  - 模板: 保留（--keep-template）")
+	case !p.templateExists:
+		fmt.Printf("  - 模板 (VM ID: %d): 不存在，跳过\n", p.templateVMID)
+	case p.templateMismatch != "":
+		fmt.Printf("  ✗ 模板 (VM ID: %d): %s\n", p.templateVMID, p.templateMismatch)
+	case len(p.templateUsers) > 0:
+		fmt.Printf("  - 模板 (VM ID: %d): 仍被其他虚拟机使用，保留: %s\n", p.templateVMID, strings.Join(p.templateUsers, ", "))
+	default:
+		fmt.Printf("  ✓ 模板 (VM ID: %d): 删除\n", p.templateVMID)
+	}
+
+	if opts.KeepConfig {
+		fmt.Printf("  - 配置目录 %s: 保留（--keep-config），清除部署状态\n", d.config.ConfigDir())
+	} else {
+		fmt.Printf("  ✓ 配置目录: %s\n", d.config.ConfigDir())
+	}
+	if opts.KeepImage {
+		fmt.Printf("  - 镜像文件 %s: 保留（--keep-image）\n", d.imageFile())
+	} else {
+		fmt.Printf("  ✓ 镜像文件: %s\n", d.imageFile())
+	}
+}
+
+// Destroy 按计划删除虚拟机、模板、配置目录和镜像
+// 设置了 Archive 时先归档配置目录，归档失败则不删除任何资源
+func (d *Deployer) Destroy(plan *DestroyPlan) error {
+	opts := plan.opts
+	if opts.Archive != "" {
+		path, err := d.ArchiveConfig(opts.Archive)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✓ 配置目录已归档: %s\n", path)
+	}
+
+	// 工作节点先于控制平面删除
+	targets := append([]destroyTarget(nil), plan.targets...)
+	sort.SliceStable(targets, func(i, j int) bool {
+		return targets[i].node.Role == "worker" && targets[j].node.Role != "worker"
+	})
+
+	// failed 删除失败的虚拟机数，skipped 归属检查不通过而跳过的节点数
+	failed, skipped := 0, 0
+	for _, t := range targets {
+		if t.mismatch != "" && !opts.IgnoreOwnership {
+			skipped++
+			continue
+		}
+		node := t.node
+		if t.exists {
+			fmt.Printf("  销毁节点: %s (VM ID: %d)\n", node.Name, node.VMID)
+			if t.running {
+				if err := d.backend.StopVM(node.VMID); err != nil {
+					fmt.Printf("  ⚠️  停止节点 %s 失败: %v\n", node.Name, err)
+				}
+				time.Sleep(1 * time.Second)
+			}
+			if err := d.backend.DestroyVM(node.VMID); err != nil {
+				fmt.Printf("  ⚠️  删除节点 %s 失败: %v\n", node.Name, err)
+				failed++
+				continue
+			}
+		}
+
+		if opts.partial() {
+			if err := d.deleteKubeNode(node.Name); err != nil {
+				fmt.Printf("  ⚠️  %v\n", err)
+			}
+		}
+		// 删除失败时部署状态需要只记录仍存在的虚拟机，因此每删除一个就更新
+		if err := d.recordNodeRemoved(node.Name); err != nil {
+			return err
+		}
+	}
+
+	if opts.partial() {
+		if failed > 0 || skipped > 0 {
+			return destroyIncomplete(failed, skipped)
+		}
+		fmt.Println("✓ 节点已销毁，仍保留在配置文件中，运行 deploy --from-step create-nodes 可重新创建，永久移除请使用 node remove")
+		return nil
+	}
+
+	// 删除模板
+	switch {
+	case plan.destroysTemplate():
+		fmt.Printf("  删除模板 (VM ID: %d)\n", plan.templateVMID)
+		if err := d.backend.DestroyVM(plan.templateVMID); err != nil {
+			fmt.Printf("  ⚠️  删除模板失败: %v\n", err)
+			failed++
+		}
+	case len(plan.templateUsers) > 0:
+		fmt.Printf("  保留模板 (VM ID: %d)，仍被其他虚拟机使用\n", plan.templateVMID)
+	}
+
+	// 仍有虚拟机未删除时保留配置目录：secrets、talosconfig 和部署状态是管理这些虚拟机的唯一凭据
+	configDir := d.config.ConfigDir()
+	if failed > 0 || skipped > 0 {
+		fmt.Printf("  保留配置目录: %s，部署状态中仍记录未删除的资源，处理后可再次运行 destroy\n", configDir)
+		return destroyIncomplete(failed, skipped)
+	}
+
+	// 清理配置文件
+	if opts.KeepConfig {
+		// 部署状态描述的资源已全部删除，保留的 secrets 可用于重新部署同一集群
+		os.Remove(state.Path(configDir))
+		fmt.Printf("  保留配置目录: %s\n", configDir)
+	} else {
+		os.RemoveAll(configDir)
+	}
+	if !opts.KeepImage {
+		os.Remove(d.imageFile())
+	}
+
+	fmt.Println("✓ 清理完成")
+	return nil
+}
+
+// destroyIncomplete 返回销毁未完成的错误
+func destroyIncomplete(failed, skipped int) error {
+	if skipped == 0 {
+		return fmt.Errorf("%d 个虚拟机删除失败", failed)
+	}
+	return fmt.Errorf("%d 个虚拟机删除失败，%d 个节点归属检查不通过未删除", failed, skipped)
+}
+
+// ArchiveConfig 将配置目录打包为 dir 下带时间戳的 tar.gz 文件，返回文件路径
+// 归档中包含 secrets 和访问凭据，文件权限为 0600
+func (d *Deployer) ArchiveConfig(dir string) (string, error) {
+	configDir := d.config.ConfigDir()
+	if _, err := os.Stat(configDir); err != nil {
+		return "", fmt.Errorf("配置目录不存在: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("创建归档目录失败: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-config-%s.tar.gz", d.config.ClusterName, time.Now().Format(archiveTimeFormat))
+	path := filepath.Join(dir, name)
+	partial := path + ".partial"
+	if err := writeTarGz(partial, configDir); err != nil {
+		os.Remove(partial)
+		return "", fmt.Errorf("归档配置目录失败: %w", err)
+	}
+	if err := os.Rename(partial, path); err != nil {
+		os.Remove(partial)
+		return "", fmt.Errorf("归档配置目录失败: %w", err)
+	}
+	return path, nil
+}
+
+// writeTarGz 将 src 目录写入 tar.gz 文件，归档内路径以 src 的目录名开头
+func writeTarGz(path, src string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	base := filepath.Base(filepath.Clean(src))
+	err = filepath.Walk(src, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(filepath.Join(base, rel))
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		in, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,365 @@
+package deployer
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// etcdBackupTimeFormat 备份文件名中的时间格式，按字典序即按时间排序
+const etcdBackupTimeFormat = "20060102-150405"
+
+// EtcdBackup etcd 备份文件
+type EtcdBackup struct {
+	Path   string    `json:"path" yaml:"path"`
+	Time   time.Time `json:"time" yaml:"time"`
+	Size   int64     `json:"size" yaml:"size"`
+	SHA256 string    `json:"sha256,omitempty" yaml:"sha256,omitempty"` // 校验文件中记录的 SHA-256
+}
+
+// etcdBackupPrefix 返回本集群备份文件名前缀，同一目录可存放多个集群的备份
+func (d *Deployer) etcdBackupPrefix() string {
+	return "etcd-" + d.config.ClusterName + "-"
+}
+
+// isEtcdHealthy 判断控制平面节点上的 etcd 成员是否健康
+func (d *Deployer) isEtcdHealthy(node config.NodeSpec) bool {
+	_, err := d.talosOutput(15*time.Second,
+		"--nodes", node.IPAddress, "--endpoints", node.IPAddress, "etcd", "status")
+	return err == nil
+}
+
+// firstHealthyControlPlane 返回第一个 etcd 健康的控制平面节点
+func (d *Deployer) firstHealthyControlPlane() (config.NodeSpec, error) {
+	for _, node := range d.config.Nodes.ControlPlanes {
+		if d.isEtcdHealthy(node) {
+			return node, nil
+		}
+	}
+	return config.NodeSpec{}, fmt.Errorf("没有 etcd 健康的控制平面节点")
+}
+
+// etcdMember etcd 集群成员
+type etcdMember struct {
+	ID       string
+	Hostname string
+	Learner  bool
+}
+
+// etcdMembers 通过 peer 节点列出 etcd 成员
+func (d *Deployer) etcdMembers(peer config.NodeSpec) ([]etcdMember, error) {
+	out, err := d.talosOutput(15*time.Second,
+		"--nodes", peer.IPAddress, "--endpoints", peer.IPAddress, "etcd", "members")
+	if err != nil {
+		return nil, fmt.Errorf("获取 etcd 成员失败: %w", err)
+	}
+	return parseEtcdMembers(string(out)), nil
+}
+
+// parseEtcdMembers 解析 talosctl etcd members 的输出
+// 列依次为 NODE、ID、HOSTNAME、PEER URLS、CLIENT URLS、LEARNER
+func parseEtcdMembers(out string) []etcdMember {
+	var members []etcdMember
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] == "NODE" {
+			continue
+		}
+		members = append(members, etcdMember{
+			ID:       fields[1],
+			Hostname: fields[2],
+			Learner:  fields[len(fields)-1] == "true",
+		})
+	}
+	return members
+}
+
+// BackupEtcd 通过第一个 etcd 健康的控制平面创建 etcd 快照
+// 快照以时间戳命名并附带 .sha256 校验文件，完成后按保留数量清理旧备份
+// dir 为空时使用配置中的备份目录
+func (d *Deployer) BackupEtcd(dir string) (*EtcdBackup, error) {
+	if dir == "" {
+		dir = d.config.EtcdBackupDir()
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建备份目录失败: %w", err)
+	}
+
+	node, err := d.firstHealthyControlPlane()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	path := filepath.Join(dir, d.etcdBackupPrefix()+now.Format(etcdBackupTimeFormat)+".db")
+	fmt.Printf("💾 备份 etcd（通过控制平面 %s）\n", node.Name)
+
+	// 先写入临时文件，避免中断的快照被当作有效备份
+	partial := path + ".partial"
+	out, err := d.talosctl("etcd", "snapshot", partial,
+		"--nodes", node.IPAddress,
+		"--endpoints", node.IPAddress,
+	).CombinedOutput()
+	if err != nil {
+		os.Remove(partial)
+		return nil, fmt.Errorf("etcd 快照失败: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	sum, size, err := fileSHA256(partial)
+	if err != nil {
+		os.Remove(partial)
+		return nil, err
+	}
+	if err := os.Rename(partial, path); err != nil {
+		return nil, fmt.Errorf("保存备份失败: %w", err)
+	}
+	checksum := fmt.Sprintf("%s  %s\n", sum, filepath.Base(path))
+	if err := os.WriteFile(path+".sha256", []byte(checksum), 0600); err != nil {
+		return nil, fmt.Errorf("写入校验文件失败: %w", err)
+	}
+
+	fmt.Printf("✓ etcd 已备份到: %s (%s)\n", path, formatSize(size))
+	fmt.Printf("  SHA-256: %s\n", sum)
+
+	if err := d.pruneEtcdBackups(dir, d.config.EtcdRetention()); err != nil {
+		fmt.Printf("⚠️  清理旧备份失败: %v\n", err)
+	}
+
+	return &EtcdBackup{Path: path, Time: now, Size: size, SHA256: sum}, nil
+}
+
+// ListEtcdBackups 列出备份目录中本集群的 etcd 备份，按时间排序
+func (d *Deployer) ListEtcdBackups(dir string) ([]EtcdBackup, error) {
+	if dir == "" {
+		dir = d.config.EtcdBackupDir()
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取备份目录失败: %w", err)
+	}
+
+	prefix := d.etcdBackupPrefix()
+	var backups []EtcdBackup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".db") {
+			continue
+		}
+		t, err := time.ParseInLocation(etcdBackupTimeFormat,
+			strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".db"), time.Local)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(dir, name)
+		sum, _ := readChecksumFile(path)
+		backups = append(backups, EtcdBackup{Path: path, Time: t, Size: info.Size(), SHA256: sum})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.Before(backups[j].Time) })
+	return backups, nil
+}
+
+// pruneEtcdBackups 只保留最新的 retention 个备份
+func (d *Deployer) pruneEtcdBackups(dir string, retention int) error {
+	backups, err := d.ListEtcdBackups(dir)
+	if err != nil {
+		return err
+	}
+	if len(backups) <= retention {
+		return nil
+	}
+	for _, b := range backups[:len(backups)-retention] {
+		if err := os.Remove(b.Path); err != nil {
+			return err
+		}
+		os.Remove(b.Path + ".sha256")
+		fmt.Printf("  删除旧备份: %s\n", filepath.Base(b.Path))
+	}
+	return nil
+}
+
+// EtcdRestoreOptions etcd 恢复选项
+type EtcdRestoreOptions struct {
+	Snapshot      string // 快照文件路径
+	WipeEphemeral bool   // etcd 仍在运行时，先清除所有控制平面的 EPHEMERAL 分区
+	SkipHashCheck bool   // 快照直接复制自 etcd 数据目录时跳过完整性校验
+}
+
+// RestoreEtcd 从快照恢复 etcd（Talos 灾难恢复流程）
+// 在 etcd 尚未引导的控制平面上执行 talosctl bootstrap --recover-from，
+// 使用与 Bootstrap 相同的 talosconfig 端点和引导节点，其余控制平面随后自动加入
+func (d *Deployer) RestoreEtcd(opts EtcdRestoreOptions) error {
+	if _, err := os.Stat(opts.Snapshot); err != nil {
+		return fmt.Errorf("读取快照失败: %w", err)
+	}
+	if err := verifyChecksum(opts.Snapshot); err != nil {
+		return err
+	}
+
+	fmt.Printf("♻️  从快照恢复 etcd: %s\n", opts.Snapshot)
+
+	if err := d.configureTalosEndpoints(); err != nil {
+		return err
+	}
+	node := d.bootstrapNode()
+
+	// 恢复要求所有控制平面上的 etcd 都处于等待引导的状态
+	var running []config.NodeSpec
+	for _, cp := range d.config.Nodes.ControlPlanes {
+		if d.isEtcdBootstrapped(cp.IPAddress) {
+			running = append(running, cp)
+		}
+	}
+	if len(running) > 0 {
+		if !opts.WipeEphemeral {
+			names := make([]string, len(running))
+			for i, cp := range running {
+				names[i] = cp.Name
+			}
+			return fmt.Errorf("控制平面 %s 上的 etcd 仍在运行，恢复需要全新的 etcd；"+
+				"使用 --wipe-ephemeral 清除所有控制平面的 EPHEMERAL 分区后恢复", strings.Join(names, ", "))
+		}
+		if err := d.wipeEphemeral(); err != nil {
+			return err
+		}
+	}
+
+	if err := d.waitConfigured(node, nodeLogger{}); err != nil {
+		return fmt.Errorf("控制平面 %s 未就绪: %w", node.Name, err)
+	}
+
+	fmt.Printf("  在控制平面 %s 上从快照引导 etcd...\n", node.Name)
+	args := []string{"bootstrap",
+		"--nodes", node.IPAddress,
+		"--recover-from", opts.Snapshot,
+		"--timeout", "5m",
+	}
+	if opts.SkipHashCheck {
+		args = append(args, "--recover-skip-hash-check")
+	}
+	if out, err := d.talosctl(args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("从快照引导失败: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	if err := d.waitEtcdHealthy(nodeLogger{}); err != nil {
+		return err
+	}
+	if err := d.recordBootstrapped(); err != nil {
+		return err
+	}
+	if err := d.waitKubernetesAPI(nodeLogger{}); err != nil {
+		return err
+	}
+
+	fmt.Println("✓ etcd 已从快照恢复")
+	return nil
+}
+
+// wipeEphemeral 清除所有控制平面的 EPHEMERAL 分区并重启，使 etcd 回到等待引导的状态
+func (d *Deployer) wipeEphemeral() error {
+	fmt.Println("  清除控制平面 EPHEMERAL 分区...")
+	for _, cp := range d.config.Nodes.ControlPlanes {
+		fmt.Printf("  重置节点: %s\n", cp.Name)
+		out, err := d.talosctl("reset",
+			"--nodes", cp.IPAddress,
+			"--endpoints", cp.IPAddress,
+			"--graceful=false",
+			"--reboot",
+			"--system-labels-to-wipe", "EPHEMERAL",
+		).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("重置节点 %s 失败: %w: %s", cp.Name, err, strings.TrimSpace(string(out)))
+		}
+	}
+	for _, cp := range d.config.Nodes.ControlPlanes {
+		if err := d.waitConfigured(cp, nodeLogger{}); err != nil {
+			return fmt.Errorf("控制平面 %s 未就绪: %w", cp.Name, err)
+		}
+	}
+	return nil
+}
+
+// fileSHA256 计算文件的 SHA-256 和大小
+func fileSHA256(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("计算校验和失败: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// readChecksumFile 读取 sha256sum 格式的校验文件 <path>.sha256
+func readChecksumFile(path string) (string, error) {
+	f, err := os.Open(path + ".sha256")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("校验文件 %s.sha256 为空", path)
+}
+
+// verifyChecksum 存在校验文件时校验快照完整性
+func verifyChecksum(path string) error {
+	expected, err := readChecksumFile(path)
+	if os.IsNotExist(err) {
+		fmt.Printf("⚠️  未找到校验文件 %s.sha256，跳过校验\n", path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	actual, _, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("快照 %s 校验失败: 期望 %s，实际 %s", path, expected, actual)
+	}
+	fmt.Println("✓ 快照校验通过")
+	return nil
+}
+
+// formatSize 以易读的单位格式化字节数
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
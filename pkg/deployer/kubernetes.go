@@ -0,0 +1,251 @@
+package deployer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// kubeconfigPath 返回集群 kubeconfig 路径
+func (d *Deployer) kubeconfigPath() string {
+	return filepath.Join(d.config.ConfigDir(), "kubeconfig")
+}
+
+// kubectl 构建使用集群 kubeconfig 的 kubectl 命令
+func (d *Deployer) kubectl(args ...string) *exec.Cmd {
+	return d.command("kubectl", append([]string{"--kubeconfig", d.kubeconfigPath()}, args...)...)
+}
+
+// ServerVersion 返回运行中的 Kubernetes API Server 版本，如 v1.29.3
+func (d *Deployer) ServerVersion() (string, error) {
+	out, err := d.kubectl("version", "-o", "json").Output()
+	if err != nil {
+		return "", fmt.Errorf("获取 Kubernetes 版本失败: %w", err)
+	}
+
+	var v struct {
+		ServerVersion struct {
+			GitVersion string `json:"gitVersion"`
+		} `json:"serverVersion"`
+	}
+	if err := json.Unmarshal(out, &v); err != nil {
+		return "", fmt.Errorf("解析 Kubernetes 版本失败: %w", err)
+	}
+	return v.ServerVersion.GitVersion, nil
+}
+
+// KubeletVersions 返回各节点的 kubelet 版本
+func (d *Deployer) KubeletVersions() (map[string]string, error) {
+	out, err := d.kubectl("get", "nodes", "-o", "json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("获取节点信息失败: %w", err)
+	}
+
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				NodeInfo struct {
+					KubeletVersion string `json:"kubeletVersion"`
+				} `json:"nodeInfo"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("解析节点信息失败: %w", err)
+	}
+
+	versions := make(map[string]string, len(list.Items))
+	for _, item := range list.Items {
+		versions[item.Metadata.Name] = item.Status.NodeInfo.KubeletVersion
+	}
+	return versions, nil
+}
+
+// versionMatches 判断运行版本是否符合配置版本
+// 配置只指定 major.minor 时只比较 minor
+func versionMatches(configured, running string) bool {
+	cv, err := config.ParseVersion(configured)
+	if err != nil {
+		return false
+	}
+	rv, err := config.ParseVersion(running)
+	if err != nil {
+		return false
+	}
+	if cv.CompareMinor(rv) != 0 {
+		return false
+	}
+	return !cv.HasPatch || cv.Patch == rv.Patch
+}
+
+// checkKubernetesVersion 对比配置的 Kubernetes 版本与运行版本，返回不一致项
+func (d *Deployer) checkKubernetesVersion() ([]string, error) {
+	configured := d.config.KubernetesVersion
+	if configured == "" {
+		return nil, nil
+	}
+
+	var mismatches []string
+
+	server, err := d.ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	if !versionMatches(configured, server) {
+		mismatches = append(mismatches, fmt.Sprintf("API Server: %s（配置: %s）", server, configured))
+	}
+
+	kubelets, err := d.KubeletVersions()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(kubelets))
+	for name := range kubelets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !versionMatches(configured, kubelets[name]) {
+			mismatches = append(mismatches, fmt.Sprintf("节点 %s kubelet: %s（配置: %s）", name, kubelets[name], configured))
+		}
+	}
+
+	return mismatches, nil
+}
+
+// kubernetesComponents 按显示顺序排列的 Kubernetes 组件，kube-system 中同名容器的镜像标签即其版本
+var kubernetesComponents = []string{"kube-apiserver", "kube-controller-manager", "kube-scheduler", "kube-proxy", "kubelet"}
+
+// ComponentVersion Kubernetes 组件在某个节点上的版本
+type ComponentVersion struct {
+	Component string
+	Node      string
+	Version   string
+}
+
+// ComponentVersions 返回各节点上控制平面组件、kube-proxy 和 kubelet 的版本
+func (d *Deployer) ComponentVersions() ([]ComponentVersion, error) {
+	out, err := d.kubectl("get", "pods", "-n", "kube-system", "-o", "json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("获取 kube-system Pod 失败: %w", err)
+	}
+
+	var list struct {
+		Items []struct {
+			Spec struct {
+				NodeName   string `json:"nodeName"`
+				Containers []struct {
+					Name  string `json:"name"`
+					Image string `json:"image"`
+				} `json:"containers"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("解析 Pod 信息失败: %w", err)
+	}
+
+	var versions []ComponentVersion
+	for _, item := range list.Items {
+		for _, c := range item.Spec.Containers {
+			if !isKubernetesComponent(c.Name) {
+				continue
+			}
+			version := c.Image
+			if i := strings.LastIndex(c.Image, ":"); i >= 0 {
+				version = c.Image[i+1:]
+			}
+			versions = append(versions, ComponentVersion{Component: c.Name, Node: item.Spec.NodeName, Version: version})
+		}
+	}
+
+	kubelets, err := d.KubeletVersions()
+	if err != nil {
+		return nil, err
+	}
+	for node, version := range kubelets {
+		versions = append(versions, ComponentVersion{Component: "kubelet", Node: node, Version: version})
+	}
+
+	order := make(map[string]int, len(kubernetesComponents))
+	for i, c := range kubernetesComponents {
+		order[c] = i
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if versions[i].Component != versions[j].Component {
+			return order[versions[i].Component] < order[versions[j].Component]
+		}
+		return versions[i].Node < versions[j].Node
+	})
+	return versions, nil
+}
+
+// isKubernetesComponent 判断容器名是否为需要报告版本的 Kubernetes 组件
+func isKubernetesComponent(name string) bool {
+	for _, c := range kubernetesComponents {
+		if c == name && c != "kubelet" {
+			return true
+		}
+	}
+	return false
+}
+
+// printComponentVersions 按组件和节点打印版本
+func printComponentVersions(versions []ComponentVersion) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, v := range versions {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", v.Component, v.Node, v.Version)
+	}
+	w.Flush()
+}
+
+// kubeNodeExists 判断 Kubernetes 中是否存在该节点对象
+func (d *Deployer) kubeNodeExists(name string) (bool, error) {
+	out, err := d.kubectl("get", "node", name, "--ignore-not-found", "-o", "name").CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("查询节点 %s 失败: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)) != "", nil
+}
+
+// drainNode 将节点标记为不可调度并驱逐其上的 Pod
+func (d *Deployer) drainNode(name string) error {
+	if out, err := d.kubectl("cordon", name).CombinedOutput(); err != nil {
+		return fmt.Errorf("cordon 节点 %s 失败: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	out, err := d.kubectl("drain", name,
+		"--ignore-daemonsets",
+		"--delete-emptydir-data",
+		"--timeout", "5m",
+	).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("drain 节点 %s 失败: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// deleteKubeNode 删除 Kubernetes 节点对象
+func (d *Deployer) deleteKubeNode(name string) error {
+	if out, err := d.kubectl("delete", "node", name, "--ignore-not-found").CombinedOutput(); err != nil {
+		return fmt.Errorf("删除 Kubernetes 节点 %s 失败: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// uncordonNode 恢复节点调度
+func (d *Deployer) uncordonNode(name string) error {
+	if out, err := d.kubectl("uncordon", name).CombinedOutput(); err != nil {
+		return fmt.Errorf("uncordon 节点 %s 失败: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
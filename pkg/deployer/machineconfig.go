@@ -0,0 +1,210 @@
+package deployer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// roleConfigFile 返回节点角色对应的基础配置文件
+func roleConfigFile(configDir string, node config.NodeSpec) string {
+	if node.Role == "controlplane" {
+		return filepath.Join(configDir, "controlplane.yaml")
+	}
+	return filepath.Join(configDir, "worker.yaml")
+}
+
+// nodesDir 返回节点专属配置目录
+func (d *Deployer) nodesDir() string {
+	return filepath.Join(d.config.ConfigDir(), "nodes")
+}
+
+// nodeConfigPath 返回节点专属配置文件路径: <configDir>/nodes/<name>.yaml
+func (d *Deployer) nodeConfigPath(node config.NodeSpec) string {
+	return filepath.Join(d.nodesDir(), node.Name+".yaml")
+}
+
+// nodePatchPath 返回节点 patch 文件路径，保留在磁盘上便于审查和对比
+func (d *Deployer) nodePatchPath(node config.NodeSpec) string {
+	return filepath.Join(d.nodesDir(), node.Name+".patch.yaml")
+}
+
+// buildNodePatch 构建节点的主机名、静态地址、默认路由、DNS、VIP、安装镜像和节点标签 patch
+func (d *Deployer) buildNodePatch(node config.NodeSpec) ([]byte, error) {
+	cidr, err := d.config.Network.NodeCIDR(node)
+	if err != nil {
+		return nil, err
+	}
+
+	iface := map[string]interface{}{
+		"deviceSelector": map[string]interface{}{"physical": true},
+		"dhcp":           false,
+		"addresses":      []string{cidr},
+		"routes": []interface{}{
+			map[string]interface{}{
+				"network": "0.0.0.0/0",
+				"gateway": d.config.Network.Gateway,
+			},
+		},
+	}
+	// 控制平面节点共享 VIP，由 Talos 在节点间自动漂移
+	if node.Role == "controlplane" && d.config.Network.VIP != "" {
+		iface["vip"] = map[string]interface{}{"ip": d.config.Network.VIP}
+	}
+
+	network := map[string]interface{}{
+		"hostname":   node.Name,
+		"interfaces": []interface{}{iface},
+	}
+	if nameservers := d.config.Network.Nameservers(); len(nameservers) > 0 {
+		network["nameservers"] = nameservers
+	}
+
+	machine := map[string]interface{}{
+		"network": network,
+		// 安装镜像跟随 talos_version，升级后重新生成的配置不会回退版本
+		"install": map[string]interface{}{"image": d.config.InstallImage(d.config.TalosVersion)},
+	}
+	if len(node.Labels) > 0 {
+		machine["nodeLabels"] = node.Labels
+	}
+
+	// yaml.v3 按键名排序输出 map，保证多次生成结果一致
+	return yaml.Marshal(map[string]interface{}{"machine": machine})
+}
+
+// nodeConfigInputHash 计算生成节点配置的全部输入的哈希：基础配置的生成参数、secrets、
+// 角色配置文件、节点 patch 和用户 patch，任一变化都意味着重新生成的节点配置会不同
+func (d *Deployer) nodeConfigInputHash(node config.NodeSpec) (string, error) {
+	h := sha256.New()
+	write := func(name string, data []byte) {
+		fmt.Fprintf(h, "%s %d\n", name, len(data))
+		h.Write(data)
+	}
+	readFile := func(path string) ([]byte, error) {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("读取 %s 失败: %w", path, err)
+		}
+		return data, nil
+	}
+
+	// 基础配置由 talosctl gen config 根据这些参数和 secrets 生成
+	write("cluster", []byte(d.config.ClusterName))
+	write("endpoint", []byte(d.config.ControlPlaneEndpoint()))
+	write("kubernetes", []byte(d.config.KubernetesVersionFull()))
+	if d.config.Registry != nil && len(d.config.Registry.Mirrors) > 0 {
+		registry, err := d.buildRegistryPatch()
+		if err != nil {
+			return "", fmt.Errorf("构建镜像源 patch 失败: %w", err)
+		}
+		write("registry", registry)
+	}
+	for _, path := range []string{d.SecretsPath(), roleConfigFile(d.config.ConfigDir(), node)} {
+		data, err := readFile(path)
+		if err != nil {
+			return "", err
+		}
+		write(filepath.Base(path), data)
+	}
+
+	patch, err := d.buildNodePatch(node)
+	if err != nil {
+		return "", fmt.Errorf("构建节点 %s 的配置 patch 失败: %w", node.Name, err)
+	}
+	write("node", patch)
+
+	for _, p := range d.config.NodePatches(node) {
+		if p.File == "" {
+			write("inline", []byte(p.Inline))
+			continue
+		}
+		data, err := readFile(d.config.ResolvePath(p.File))
+		if err != nil {
+			return "", err
+		}
+		write("file "+p.File, data)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// RenderNodeConfigs 基于现有角色配置重新生成所有节点的专属配置文件，并记录配置哈希
+func (d *Deployer) RenderNodeConfigs() error {
+	configDir := d.config.ConfigDir()
+	for _, name := range []string{"controlplane.yaml", "worker.yaml"} {
+		if _, err := os.Stat(filepath.Join(configDir, name)); err != nil {
+			return fmt.Errorf("未找到 %s，请先运行 deploy 生成配置: %w", name, err)
+		}
+	}
+	if err := d.renderNodeConfigs(configDir); err != nil {
+		return err
+	}
+	return d.recordConfigHashes()
+}
+
+// renderNodeConfigs 为每个节点基于角色配置生成专属配置文件
+func (d *Deployer) renderNodeConfigs(configDir string) error {
+	fmt.Println("  生成节点配置...")
+
+	if err := os.MkdirAll(d.nodesDir(), 0755); err != nil {
+		return fmt.Errorf("创建节点配置目录失败: %w", err)
+	}
+
+	expected := make(map[string]bool)
+	for _, node := range d.config.AllNodes() {
+		if err := d.renderNodeConfig(configDir, node); err != nil {
+			return err
+		}
+		expected[filepath.Base(d.nodeConfigPath(node))] = true
+		expected[filepath.Base(d.nodePatchPath(node))] = true
+	}
+
+	// 清理已从配置中移除的节点文件
+	entries, err := os.ReadDir(d.nodesDir())
+	if err != nil {
+		return fmt.Errorf("读取节点配置目录失败: %w", err)
+	}
+	for _, entry := range entries {
+		if !expected[entry.Name()] && strings.HasSuffix(entry.Name(), ".yaml") {
+			os.Remove(filepath.Join(d.nodesDir(), entry.Name()))
+		}
+	}
+
+	return nil
+}
+
+// renderNodeConfig 生成单个节点的 patch 和专属配置文件
+func (d *Deployer) renderNodeConfig(configDir string, node config.NodeSpec) error {
+	patch, err := d.buildNodePatch(node)
+	if err != nil {
+		return fmt.Errorf("构建节点 %s 的配置 patch 失败: %w", node.Name, err)
+	}
+
+	patchFile := d.nodePatchPath(node)
+	if err := os.WriteFile(patchFile, patch, 0644); err != nil {
+		return fmt.Errorf("创建 patch 文件失败: %w", err)
+	}
+
+	// 先应用节点网络 patch，再按 cluster → 角色 → 节点 的顺序应用用户 patch
+	patches := []string{"@" + patchFile}
+	for _, p := range d.config.NodePatches(node) {
+		if p.File != "" {
+			patches = append(patches, "@"+d.config.ResolvePath(p.File))
+		} else {
+			patches = append(patches, p.Inline)
+		}
+	}
+
+	if err := d.patchConfigFile(roleConfigFile(configDir, node), d.nodeConfigPath(node), patches...); err != nil {
+		return fmt.Errorf("生成节点 %s 的配置失败: %w", node.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// maintenanceNode 返回要维护的节点，使用部署状态中记录的 VM ID
+func (d *Deployer) maintenanceNode(name string) (config.NodeSpec, error) {
+	nodes, err := d.selectManagedNodes(NodeSelector{Names: []string{name}})
+	if err != nil {
+		return config.NodeSpec{}, err
+	}
+	return nodes[0], nil
+}
+
+// EnterMaintenance 让节点进入维护：cordon 并 drain 节点后优雅关机
+// 控制平面关机会破坏 etcd quorum 时拒绝执行
+func (d *Deployer) EnterMaintenance(name string) error {
+	node, err := d.maintenanceNode(name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🔧 节点进入维护: %s (VM ID: %d)\n", node.Name, node.VMID)
+
+	status, err := d.backend.VMStatus(node.VMID)
+	if errors.Is(err, ErrVMNotFound) {
+		return fmt.Errorf("节点 %s 的虚拟机 (VM ID: %d) 不存在", node.Name, node.VMID)
+	}
+	if err != nil {
+		return fmt.Errorf("检查虚拟机状态失败: %w", err)
+	}
+	if err := checkOwnership(node, status); err != nil {
+		return err
+	}
+
+	if node.Role == "controlplane" {
+		if len(d.config.Nodes.ControlPlanes) == 1 {
+			fmt.Println("  ⚠️  集群只有一个控制平面，维护期间 Kubernetes API 不可用")
+		} else if err := d.checkStopQuorum([]config.NodeSpec{node}); err != nil {
+			return err
+		}
+	}
+
+	inCluster, err := d.kubeNodeExists(node.Name)
+	if err != nil {
+		return err
+	}
+	if inCluster {
+		fmt.Println("  驱逐节点上的 Pod...")
+		if err := d.drainNode(node.Name); err != nil {
+			return fmt.Errorf("%w\n节点已标记为不可调度，可使用 kubectl uncordon %s 恢复", err, node.Name)
+		}
+	}
+
+	if err := d.shutdownNode(node, nodeLogger{}); err != nil {
+		return fmt.Errorf("%w\n节点已标记为不可调度，可运行 manage maintenance %s --end 恢复", err, node.Name)
+	}
+
+	fmt.Printf("✓ 节点 %s 已关机，可以开始维护\n", node.Name)
+	fmt.Printf("  维护完成后运行 manage maintenance %s --end 启动节点并恢复调度\n", node.Name)
+	return nil
+}
+
+// ExitMaintenance 结束节点维护：启动节点，等待其恢复健康后 uncordon
+func (d *Deployer) ExitMaintenance(name string) error {
+	node, err := d.maintenanceNode(name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🔧 节点结束维护: %s (VM ID: %d)\n", node.Name, node.VMID)
+
+	log := nodeLogger{}
+	if err := d.startNode(node, log); err != nil {
+		return err
+	}
+	if err := d.waitConfigured(node, log); err != nil {
+		return err
+	}
+	if node.Role == "controlplane" {
+		if err := d.waitEtcdHealthy(log); err != nil {
+			return err
+		}
+	}
+
+	inCluster, err := d.kubeNodeExists(node.Name)
+	if err != nil {
+		return err
+	}
+	if inCluster {
+		bootID, err := d.nodeBootID(node)
+		if err != nil {
+			return fmt.Errorf("读取节点 boot ID 失败: %w", err)
+		}
+		if err := d.waitNodeRebooted(node, bootID, log); err != nil {
+			return err
+		}
+		if err := d.uncordonNode(node.Name); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("✓ 节点 %s 已恢复调度\n", node.Name)
+	return nil
+}
@@ -0,0 +1,323 @@
+package deployer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// 没有现有工作节点可参照时新工作节点的默认资源，与 init 向导一致
+const (
+	defaultWorkerCPU    = 4
+	defaultWorkerMemory = 4096
+	defaultWorkerDisk   = "50G"
+)
+
+// nodeNameSuffix 匹配节点名末尾的序号，如 talos-worker-2
+var nodeNameSuffix = regexp.MustCompile(`^(.*?)(\d+)$`)
+
+// NewWorkers 按现有工作节点的命名、VM ID、IP 和资源规律生成 count 个新工作节点
+// 跳过配置中已使用的名称、VM ID 和 IP，以及 Proxmox 上已存在的 VM ID
+func (d *Deployer) NewWorkers(count int) ([]config.NodeSpec, error) {
+	names := make(map[string]bool)
+	ips := make(map[string]bool)
+	vmIDs := map[int]bool{d.config.Proxmox.TemplateVMID: true}
+	for _, node := range d.config.AllNodes() {
+		names[node.Name] = true
+		ips[node.IPAddress] = true
+		vmIDs[node.VMID] = true
+	}
+	ips[d.config.Network.Gateway] = true
+	if d.config.Network.VIP != "" {
+		ips[d.config.Network.VIP] = true
+	}
+
+	// 以最后一个工作节点为参照，没有工作节点时参照最后一个控制平面
+	all := d.config.AllNodes()
+	ref := all[len(all)-1]
+	prefix, index := "talos-worker-", 0
+	cpu, memory, disk := defaultWorkerCPU, defaultWorkerMemory, defaultWorkerDisk
+	if workers := d.config.Nodes.Workers; len(workers) > 0 {
+		ref = workers[len(workers)-1]
+		if m := nodeNameSuffix.FindStringSubmatch(ref.Name); m != nil {
+			prefix = m[1]
+			index, _ = strconv.Atoi(m[2])
+		}
+		cpu, memory, disk = ref.CPU, ref.Memory, ref.Disk
+	}
+
+	subnet, err := d.config.Network.Subnet()
+	if err != nil {
+		return nil, err
+	}
+
+	vmID, ip := ref.VMID, ref.IPAddress
+	var nodes []config.NodeSpec
+	for len(nodes) < count {
+		node := config.NodeSpec{Role: "worker", CPU: cpu, Memory: memory, Disk: disk}
+
+		for {
+			index++
+			node.Name = prefix + strconv.Itoa(index)
+			if !names[node.Name] {
+				break
+			}
+		}
+
+		for {
+			vmID++
+			if vmIDs[vmID] {
+				continue
+			}
+			_, err := d.backend.VMStatus(vmID)
+			if errors.Is(err, ErrVMNotFound) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("检查 VM ID %d 失败: %w", vmID, err)
+			}
+		}
+		node.VMID = vmID
+
+		for {
+			ip, err = nextIP(ip)
+			if err != nil {
+				return nil, err
+			}
+			if !subnet.Contains(net.ParseIP(ip)) {
+				return nil, fmt.Errorf("子网 %s 中没有可分配的 IP 地址", subnet)
+			}
+			if !ips[ip] {
+				break
+			}
+		}
+		node.IPAddress = ip
+
+		names[node.Name], vmIDs[node.VMID], ips[node.IPAddress] = true, true, true
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// nextIP 返回下一个 IPv4 地址
+func nextIP(ip string) (string, error) {
+	v4 := net.ParseIP(ip).To4()
+	if v4 == nil {
+		return "", fmt.Errorf("无效的 IP 地址: %q", ip)
+	}
+	n := binary.BigEndian.Uint32(v4) + 1
+	next := make(net.IP, 4)
+	binary.BigEndian.PutUint32(next, n)
+	return next.String(), nil
+}
+
+// AddWorkers 向运行中的集群添加工作节点
+// 先将节点写入配置文件，只创建新节点的虚拟机，基于已生成的 worker.yaml 生成节点配置并应用，
+// 然后等待节点在 Kubernetes 中 Ready
+func (d *Deployer) AddWorkers(nodes []config.NodeSpec) error {
+	configDir := d.config.ConfigDir()
+	if _, err := os.Stat(filepath.Join(configDir, "worker.yaml")); err != nil {
+		return fmt.Errorf("未找到 worker.yaml，请先部署集群: %w", err)
+	}
+
+	for i, node := range nodes {
+		if _, ok := d.config.FindNode(node.Name); ok {
+			return fmt.Errorf("节点 %s 已存在", node.Name)
+		}
+		nodes[i].Role = "worker"
+	}
+
+	workers := d.config.Nodes.Workers
+	d.config.Nodes.Workers = append(append([]config.NodeSpec(nil), workers...), nodes...)
+	if err := d.config.Validate(); err != nil {
+		d.config.Nodes.Workers = workers
+		return fmt.Errorf("配置验证失败: %w", err)
+	}
+	for _, node := range nodes {
+		status, err := d.backend.VMStatus(node.VMID)
+		if err == nil {
+			d.config.Nodes.Workers = workers
+			return fmt.Errorf("VM ID %d 已被虚拟机 %q 占用", node.VMID, status.Name)
+		}
+		if !errors.Is(err, ErrVMNotFound) {
+			d.config.Nodes.Workers = workers
+			return fmt.Errorf("检查 VM ID %d 失败: %w", node.VMID, err)
+		}
+	}
+
+	fmt.Printf("➕ 添加 %d 个工作节点\n", len(nodes))
+	for _, node := range nodes {
+		fmt.Printf("  %s: VM ID %d, IP %s, %d 核 / %d MB / %s\n",
+			node.Name, node.VMID, node.IPAddress, node.CPU, node.Memory, node.Disk)
+	}
+
+	if err := d.config.Save(); err != nil {
+		return fmt.Errorf("更新配置文件失败: %w", err)
+	}
+	fmt.Println("✓ 配置文件已更新")
+
+	if err := os.MkdirAll(d.nodesDir(), 0755); err != nil {
+		return fmt.Errorf("创建节点配置目录失败: %w", err)
+	}
+	for _, node := range nodes {
+		if err := d.renderNodeConfig(configDir, node); err != nil {
+			return err
+		}
+	}
+	if err := d.recordConfigHashes(); err != nil {
+		return err
+	}
+
+	err := d.forEachNode(nodes, func(node config.NodeSpec, log nodeLogger) error {
+		if err := d.createNode(node, log); err != nil {
+			return fmt.Errorf("创建节点 %s 失败: %w", node.Name, err)
+		}
+		if err := d.recordNodeCreated(node); err != nil {
+			return err
+		}
+		if err := d.configureNode(node, log); err != nil {
+			return err
+		}
+		return d.waitNodeReady(node, log)
+	})
+	if err != nil {
+		return fmt.Errorf("%w\n节点已写入配置文件，修复问题后可运行 deploy --from-step create-nodes 继续创建和配置", err)
+	}
+
+	fmt.Printf("✓ 已添加 %d 个工作节点\n", len(nodes))
+	return nil
+}
+
+// WorkerCount 返回配置中的工作节点数量
+func (d *Deployer) WorkerCount() int {
+	return len(d.config.Nodes.Workers)
+}
+
+// ScaleWorkers 将工作节点数量调整为 count
+// 扩容时按现有规律生成新节点，缩容时从最后一个工作节点开始移除
+func (d *Deployer) ScaleWorkers(count int) error {
+	if count < 0 {
+		return fmt.Errorf("工作节点数量不能为负数: %d", count)
+	}
+
+	current := len(d.config.Nodes.Workers)
+	switch {
+	case count == current:
+		fmt.Printf("✓ 已有 %d 个工作节点，无需调整\n", current)
+		return nil
+	case count > current:
+		nodes, err := d.NewWorkers(count - current)
+		if err != nil {
+			return err
+		}
+		return d.AddWorkers(nodes)
+	}
+
+	var names []string
+	for i := current - 1; i >= count; i-- {
+		names = append(names, d.config.Nodes.Workers[i].Name)
+	}
+	fmt.Printf("➖ 工作节点 %d → %d\n", current, count)
+	for _, name := range names {
+		if err := d.RemoveNode(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveNode 从集群中移除工作节点
+// 依次 cordon 并 drain 节点、通过 Talos 重置、删除 Kubernetes 节点对象、销毁虚拟机，
+// 最后从配置文件和部署状态中删除该节点
+func (d *Deployer) RemoveNode(name string) error {
+	node, ok := d.config.FindNode(name)
+	if !ok {
+		return fmt.Errorf("未找到节点: %s", name)
+	}
+	if node.Role != "worker" {
+		return fmt.Errorf("%s 是控制平面节点，移除会改变 etcd 成员，不支持通过 node remove 操作，故障节点请使用 node replace", name)
+	}
+	if s, err := d.State(); err == nil {
+		if recorded, ok := s.Lookup(name); ok && recorded.VMID != 0 {
+			node.VMID = recorded.VMID
+		}
+	}
+
+	fmt.Printf("➖ 移除节点: %s (VM ID: %d)\n", node.Name, node.VMID)
+
+	// 先确认虚拟机属于该集群的这个节点，避免误删其他集群的同名节点
+	status, err := d.backend.VMStatus(node.VMID)
+	vmExists := err == nil
+	if vmExists {
+		if err := d.checkNodeOwnership(node, status); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, ErrVMNotFound) {
+		return fmt.Errorf("检查虚拟机状态失败: %w", err)
+	}
+
+	inCluster, err := d.kubeNodeExists(node.Name)
+	if err != nil {
+		return err
+	}
+	if inCluster {
+		fmt.Println("  驱逐节点上的 Pod...")
+		if err := d.drainNode(node.Name); err != nil {
+			return fmt.Errorf("%w\n节点已标记为不可调度，可使用 kubectl uncordon %s 恢复", err, node.Name)
+		}
+	}
+
+	if vmExists && status.Status == "running" && d.isNodeConfigured(node) {
+		fmt.Println("  重置 Talos...")
+		if err := d.resetNode(node); err != nil {
+			fmt.Printf("  ⚠️  重置节点失败，继续销毁虚拟机: %v\n", err)
+		}
+	}
+
+	if inCluster {
+		if err := d.deleteKubeNode(node.Name); err != nil {
+			return err
+		}
+	}
+
+	if vmExists {
+		fmt.Println("  销毁虚拟机...")
+		if status, err := d.backend.VMStatus(node.VMID); err == nil && status.Status == "running" {
+			if err := d.backend.StopVM(node.VMID); err != nil {
+				return fmt.Errorf("停止虚拟机失败: %w", err)
+			}
+		}
+		if err := d.backend.DestroyVM(node.VMID); err != nil {
+			return fmt.Errorf("删除虚拟机失败: %w", err)
+		}
+	}
+
+	for i, w := range d.config.Nodes.Workers {
+		if w.Name == node.Name {
+			d.config.Nodes.Workers = append(d.config.Nodes.Workers[:i:i], d.config.Nodes.Workers[i+1:]...)
+			break
+		}
+	}
+	if err := d.config.Save(); err != nil {
+		return fmt.Errorf("更新配置文件失败: %w", err)
+	}
+
+	os.Remove(d.nodeConfigPath(node))
+	os.Remove(d.nodePatchPath(node))
+	if err := d.recordNodeRemoved(node.Name); err != nil {
+		return err
+	}
+	if err := d.recordConfigHashes(); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ 节点 %s 已移除\n", node.Name)
+	return nil
+}
@@ -0,0 +1,161 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// outputMu 保证并发输出的每条消息完整打印
+var outputMu sync.Mutex
+
+// nodeLogger 节点输出，并发处理多个节点时在每行前加上节点名
+type nodeLogger struct {
+	prefix string
+}
+
+// Printf 格式化输出，前缀加在每行的缩进之后
+func (l nodeLogger) Printf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	if l.prefix == "" {
+		fmt.Print(msg)
+		return
+	}
+
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(msg, "\n") {
+		if line == "" {
+			continue
+		}
+		text := strings.TrimLeft(line, " ")
+		b.WriteString(line[:len(line)-len(text)])
+		b.WriteString(l.prefix)
+		b.WriteString(text)
+	}
+	fmt.Print(b.String())
+}
+
+// Println 输出一行
+func (l nodeLogger) Println(args ...interface{}) {
+	l.Printf("%s", fmt.Sprintln(args...))
+}
+
+// SetParallel 设置同时处理的节点数，覆盖配置文件中的 deploy.parallel
+func (d *Deployer) SetParallel(n int) {
+	d.parallel = n
+}
+
+// parallelism 返回同时处理的节点数，默认 1
+func (d *Deployer) parallelism() int {
+	n := d.parallel
+	if n <= 0 {
+		n = d.config.Deploy.Parallel
+	}
+	if n <= 0 {
+		n = 1
+	}
+	return n
+}
+
+// NodeErrors 多个节点的失败，按节点顺序排列
+type NodeErrors []*NodeError
+
+func (e NodeErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d 个节点失败:\n  - %s", len(e), strings.Join(msgs, "\n  - "))
+}
+
+func (e NodeErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Nodes 返回失败的节点名
+func (e NodeErrors) Nodes() []string {
+	names := make([]string, len(e))
+	for i, err := range e {
+		names[i] = err.Node
+	}
+	return names
+}
+
+// forEachNode 对每个节点执行 fn，最多同时处理 parallelism 个节点
+// 单个节点失败不影响其他节点，所有失败汇总为 NodeErrors 返回
+func (d *Deployer) forEachNode(nodes []config.NodeSpec, fn func(node config.NodeSpec, log nodeLogger) error) error {
+	parallel := d.parallelism()
+	errs := make([]*NodeError, len(nodes))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, node := range nodes {
+		log := nodeLogger{}
+		if parallel > 1 {
+			log.prefix = "[" + node.Name + "] "
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, node config.NodeSpec, log nodeLogger) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := fn(node, log); err != nil {
+				var nodeErr *NodeError
+				if !errors.As(err, &nodeErr) {
+					nodeErr = &NodeError{Node: node.Name, Err: err}
+				}
+				errs[i] = nodeErr
+			}
+		}(i, node, log)
+	}
+	wg.Wait()
+
+	var failed NodeErrors
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// splitByRole 将节点分为控制平面和工作节点，同角色内保持原顺序
+// 只记录在部署状态中且角色未知的节点按控制平面处理
+func splitByRole(nodes []config.NodeSpec) (controlPlanes, workers []config.NodeSpec) {
+	for _, node := range nodes {
+		if node.Role == "worker" {
+			workers = append(workers, node)
+		} else {
+			controlPlanes = append(controlPlanes, node)
+		}
+	}
+	return controlPlanes, workers
+}
+
+// forEachNodeByRole 先处理所有控制平面节点，全部成功后再处理工作节点
+func (d *Deployer) forEachNodeByRole(nodes []config.NodeSpec, fn func(node config.NodeSpec, log nodeLogger) error) error {
+	controlPlanes, workers := splitByRole(nodes)
+	if err := d.forEachNode(controlPlanes, fn); err != nil {
+		return err
+	}
+	return d.forEachNode(workers, fn)
+}
@@ -0,0 +1,183 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"talos-proxmox-deployer/pkg/state"
+)
+
+// 部署步骤
+const (
+	StepPrepareImage   = "prepare-image"
+	StepCreateTemplate = "create-template"
+	StepCreateNodes    = "create-nodes"
+	StepGenerateConfig = "generate-config"
+	StepApplyConfig    = "apply-config"
+	StepBootstrap      = "bootstrap"
+)
+
+// Steps 按执行顺序排列的部署步骤
+var Steps = []string{
+	StepPrepareImage,
+	StepCreateTemplate,
+	StepCreateNodes,
+	StepGenerateConfig,
+	StepApplyConfig,
+	StepBootstrap,
+}
+
+// NodeError 某个节点上的失败，用于记录断点
+type NodeError struct {
+	Node string
+	Err  error
+}
+
+func (e *NodeError) Error() string { return e.Err.Error() }
+func (e *NodeError) Unwrap() error { return e.Err }
+
+// DeployOptions 部署选项
+type DeployOptions struct {
+	Skip     map[string]bool // 跳过的步骤
+	Resume   bool            // 从上次失败的步骤和节点继续
+	FromStep string          // 从指定步骤开始，之前的步骤全部跳过
+	Parallel int             // 同时处理的节点数，0 表示使用配置文件中的设置
+}
+
+// ValidStep 检查步骤名是否有效
+func ValidStep(step string) error {
+	for _, s := range Steps {
+		if s == step {
+			return nil
+		}
+	}
+	return fmt.Errorf("无效的步骤: %s，可选: %s", step, strings.Join(Steps, ", "))
+}
+
+// Deploy 按顺序执行部署步骤，每个步骤完成后写入断点
+func (d *Deployer) Deploy(opts DeployOptions) error {
+	s, err := d.State()
+	if err != nil {
+		return err
+	}
+
+	if opts.Parallel > 0 {
+		d.SetParallel(opts.Parallel)
+	}
+
+	fromIndex := 0
+	if opts.FromStep != "" {
+		if err := ValidStep(opts.FromStep); err != nil {
+			return err
+		}
+		for i, step := range Steps {
+			if step == opts.FromStep {
+				fromIndex = i
+			}
+		}
+	}
+
+	if opts.Resume {
+		if !s.Exists() {
+			return fmt.Errorf("未找到部署状态，无法恢复，请直接运行 deploy")
+		}
+		if s.Failure != nil {
+			fmt.Printf("↩️  从上次失败处继续: 步骤 %s", s.Failure.Step)
+			if s.Failure.Node != "" {
+				fmt.Printf("，节点 %s", s.Failure.Node)
+			}
+			fmt.Printf("\n   上次错误: %s\n\n", s.Failure.Error)
+		}
+		d.resume = true
+	} else {
+		// 全新部署：清除之前的步骤断点，节点级状态保留
+		if err := d.updateState(func(s *state.State) {
+			s.CompletedSteps = nil
+			s.Failure = nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	run := map[string]func() error{
+		StepPrepareImage:   d.PrepareImage,
+		StepCreateTemplate: d.CreateTemplate,
+		StepCreateNodes:    d.CreateNodes,
+		StepGenerateConfig: d.GenerateConfig,
+		StepApplyConfig:    d.ApplyConfig,
+		StepBootstrap:      d.Bootstrap,
+	}
+
+	for i, step := range Steps {
+		switch {
+		case i < fromIndex:
+			fmt.Printf("⏭️  跳过 %s（--from-step %s）\n", step, opts.FromStep)
+			continue
+		case opts.Skip[step]:
+			fmt.Printf("⏭️  跳过 %s\n", step)
+			continue
+		case opts.Resume && opts.FromStep == "" && s.StepCompleted(step):
+			fmt.Printf("✓ %s 已完成，跳过\n", step)
+			continue
+		}
+
+		if err := run[step](); err != nil {
+			if recErr := d.recordFailure(step, err); recErr != nil {
+				fmt.Printf("⚠️  %v\n", recErr)
+			}
+			return &StepError{Step: step, Err: err}
+		}
+
+		if err := d.updateState(func(s *state.State) {
+			s.MarkStep(step)
+			s.Failure = nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StepError 部署步骤失败
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string { return fmt.Sprintf("步骤 %s 失败: %v", e.Step, e.Err) }
+func (e *StepError) Unwrap() error { return e.Err }
+
+// recordFailure 记录失败的步骤和节点
+func (d *Deployer) recordFailure(step string, err error) error {
+	failure := &state.Failure{
+		Step:  step,
+		Error: err.Error(),
+		Time:  time.Now().UTC().Truncate(time.Second),
+	}
+	var nodeErrs NodeErrors
+	var nodeErr *NodeError
+	if errors.As(err, &nodeErrs) {
+		failure.Node = strings.Join(nodeErrs.Nodes(), ",")
+	} else if errors.As(err, &nodeErr) {
+		failure.Node = nodeErr.Node
+	}
+	return d.updateState(func(s *state.State) {
+		s.Failure = failure
+	})
+}
+
+// nodeDone 判断 --resume 时节点是否已完成指定检查点
+func (d *Deployer) nodeDone(name string, vmID int, check func(n state.NodeState) bool) bool {
+	if !d.resume {
+		return false
+	}
+	s, err := d.State()
+	if err != nil {
+		return false
+	}
+	n, ok := s.Lookup(name)
+	return ok && n.VMID == vmID && check(n)
+}
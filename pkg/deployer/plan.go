@@ -0,0 +1,264 @@
+package deployer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// PlanAction 计划中的操作类型
+type PlanAction string
+
+const (
+	ActionCreate   PlanAction = "create"
+	ActionUpdate   PlanAction = "update"
+	ActionDelete   PlanAction = "delete"
+	ActionNoop     PlanAction = "no-op"
+	ActionConflict PlanAction = "conflict"
+)
+
+// PlanItem 计划中的一项资源变更
+type PlanItem struct {
+	Action   PlanAction       `json:"action"`
+	Resource string           `json:"resource"`
+	VMID     int              `json:"vm_id,omitempty"`
+	Reason   string           `json:"reason,omitempty"`
+	Changes  []ResourceChange `json:"changes,omitempty"`
+}
+
+// Plan deploy 将要执行的变更
+type Plan struct {
+	Cluster string     `json:"cluster"`
+	Items   []PlanItem `json:"items"`
+}
+
+// HasChanges 判断计划中是否有需要执行的变更
+func (p *Plan) HasChanges() bool {
+	for _, item := range p.Items {
+		if item.Action != ActionNoop {
+			return true
+		}
+	}
+	return false
+}
+
+// HasConflicts 判断计划中是否有无法自动处理的冲突
+func (p *Plan) HasConflicts() bool {
+	for _, item := range p.Items {
+		if item.Action == ActionConflict {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary 统计各类操作数量
+func (p *Plan) Summary() map[PlanAction]int {
+	summary := make(map[PlanAction]int)
+	for _, item := range p.Items {
+		summary[item.Action]++
+	}
+	return summary
+}
+
+func (p *Plan) add(item PlanItem) {
+	p.Items = append(p.Items, item)
+}
+
+// Plan 对比配置与 Proxmox 和本地配置目录的实际状态，计算 deploy 将执行的变更
+// 只读取状态，不做任何修改
+func (d *Deployer) Plan() (*Plan, error) {
+	plan := &Plan{Cluster: d.config.ClusterName}
+
+	if err := d.planTemplate(plan); err != nil {
+		return nil, err
+	}
+	for _, node := range d.config.AllNodes() {
+		if err := d.planNode(plan, node); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.planRemovedNodes(plan); err != nil {
+		return nil, err
+	}
+	if err := d.planConfigDir(plan); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// planTemplate 计算模板的变更
+func (d *Deployer) planTemplate(plan *Plan) error {
+	vmID := d.config.Proxmox.TemplateVMID
+	item := PlanItem{Resource: "template", VMID: vmID}
+
+	status, err := d.backend.VMStatus(vmID)
+	switch {
+	case errors.Is(err, ErrVMNotFound):
+		imageFile := d.imageFile()
+		if _, err := os.Stat(imageFile); err != nil {
+			plan.add(PlanItem{Action: ActionCreate, Resource: "image", Reason: "下载并转换 " + imageFile})
+		}
+		item.Action = ActionCreate
+		item.Reason = "从 Talos " + d.config.TalosVersion + " 镜像创建模板"
+	case err != nil:
+		return fmt.Errorf("检查模板状态失败: %w", err)
+	case status.Template == 1:
+		item.Action = ActionNoop
+	case status.Name == templateName:
+		item.Action = ActionUpdate
+		item.Reason = "完成上次中断的模板创建"
+	default:
+		item.Action = ActionConflict
+		item.Reason = fmt.Sprintf("VM ID 已被其他虚拟机 %q 占用", status.Name)
+	}
+
+	plan.add(item)
+	return nil
+}
+
+// planNode 计算单个节点虚拟机的变更
+func (d *Deployer) planNode(plan *Plan, node config.NodeSpec) error {
+	item := PlanItem{Resource: "vm/" + node.Name, VMID: node.VMID}
+
+	status, err := d.backend.VMStatus(node.VMID)
+	if errors.Is(err, ErrVMNotFound) {
+		item.Action = ActionCreate
+		item.Reason = fmt.Sprintf("克隆模板 %d（%d 核 / %d MB / %s）",
+			d.config.Proxmox.TemplateVMID, node.CPU, node.Memory, node.Disk)
+		plan.add(item)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("检查节点 %s 状态失败: %w", node.Name, err)
+	}
+
+	if err := checkOwnership(node, status); err != nil {
+		item.Action = ActionConflict
+		item.Reason = err.Error()
+		plan.add(item)
+		return nil
+	}
+
+	vmCfg, err := d.backend.VMConfig(node.VMID)
+	if err != nil {
+		return fmt.Errorf("读取节点 %s 配置失败: %w", node.Name, err)
+	}
+	changes, err := nodeResourceChanges(node, status, vmCfg)
+	if err != nil {
+		return err
+	}
+	if tags, missing := d.mergeNodeTags(status.Tags); len(missing) > 0 {
+		changes = append(changes, ResourceChange{Field: "tags", From: status.Tags, To: tags})
+	}
+	if status.Status != "running" {
+		changes = append(changes, ResourceChange{Field: "power", From: status.Status, To: "running"})
+	}
+
+	item.Changes = changes
+	if len(changes) > 0 {
+		item.Action = ActionUpdate
+	} else {
+		item.Action = ActionNoop
+	}
+	plan.add(item)
+	return nil
+}
+
+// planRemovedNodes 列出部署状态中记录、但已从配置中移除或更换了 VMID 的虚拟机
+func (d *Deployer) planRemovedNodes(plan *Plan) error {
+	s, err := d.State()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range s.NodeNames() {
+		recorded, _ := s.Lookup(name)
+		node, ok := d.config.FindNode(name)
+		switch {
+		case !ok:
+			plan.add(PlanItem{Action: ActionDelete, Resource: "vm/" + name, VMID: recorded.VMID,
+				Reason: "节点已从配置中移除（deploy 不会自动删除，请使用 destroy）"})
+		case node.VMID != recorded.VMID:
+			plan.add(PlanItem{Action: ActionDelete, Resource: "vm/" + name, VMID: recorded.VMID,
+				Reason: fmt.Sprintf("配置中的 VM ID 已改为 %d（原虚拟机仍存在时 deploy 会拒绝执行，请先 destroy --node %s）", node.VMID, name)})
+		}
+	}
+	return nil
+}
+
+// planConfigDir 计算本地配置目录和节点配置文件的变更
+func (d *Deployer) planConfigDir(plan *Plan) error {
+	if _, err := os.Stat(d.SecretsPath()); err != nil {
+		plan.add(PlanItem{Action: ActionCreate, Resource: "secrets", Reason: "生成 Talos secrets bundle"})
+	} else {
+		plan.add(PlanItem{Action: ActionNoop, Resource: "secrets"})
+	}
+
+	s, err := d.State()
+	if err != nil {
+		return err
+	}
+
+	expected := make(map[string]bool)
+	for _, node := range d.config.AllNodes() {
+		expected[node.Name] = true
+		item := PlanItem{Resource: "config/" + node.Name}
+
+		patch, err := d.buildNodePatch(node)
+		if err != nil {
+			return err
+		}
+		inputHash, err := d.nodeConfigInputHash(node)
+		if err != nil {
+			return err
+		}
+		recorded, _ := s.Lookup(node.Name)
+
+		current, err := os.ReadFile(d.nodePatchPath(node))
+		if err == nil {
+			_, err = os.Stat(d.nodeConfigPath(node))
+		}
+		switch {
+		case os.IsNotExist(err):
+			item.Action = ActionCreate
+		case err != nil:
+			return fmt.Errorf("读取节点 %s 的配置失败: %w", node.Name, err)
+		case !bytes.Equal(current, patch):
+			item.Action = ActionUpdate
+			item.Reason = "节点网络或标签配置已变化"
+		case recorded.InputHash == "":
+			item.Action = ActionUpdate
+			item.Reason = "部署状态中没有记录生成配置时的输入，无法确认是否变化"
+		case recorded.InputHash != inputHash:
+			item.Action = ActionUpdate
+			item.Reason = "patches、基础配置或 secrets 已变化"
+		default:
+			item.Action = ActionNoop
+		}
+		plan.add(item)
+	}
+
+	// 已从配置中移除的节点
+	entries, err := os.ReadDir(d.nodesDir())
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("读取节点配置目录失败: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".patch.yaml") {
+			continue
+		}
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+		if !expected[name] {
+			plan.add(PlanItem{Action: ActionDelete, Resource: "config/" + name, Reason: "节点已从配置中移除"})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,259 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// StartNodes 启动选中的节点，先启动控制平面并等待 etcd 恢复 quorum，再启动工作节点
+func (d *Deployer) StartNodes(sel NodeSelector) error {
+	nodes, err := d.selectManagedNodes(sel)
+	if err != nil {
+		return err
+	}
+	controlPlanes, workers := splitByRole(nodes)
+
+	if len(controlPlanes) > 0 {
+		fmt.Println("  启动控制平面...")
+		if err := d.forEachNode(controlPlanes, d.startNode); err != nil {
+			return fmt.Errorf("启动控制平面失败，工作节点未启动: %w", err)
+		}
+		// etcd 未引导时没有 quorum 可等
+		if s, err := d.State(); err == nil && s.Bootstrapped {
+			if err := d.waitEtcdQuorum(nodeLogger{}); err != nil {
+				return fmt.Errorf("%w\n工作节点未启动，etcd 恢复后可再次运行 manage start", err)
+			}
+		}
+	}
+
+	if len(workers) > 0 {
+		fmt.Println("  启动工作节点...")
+		if err := d.forEachNode(workers, d.startNode); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("✓ 节点启动完成")
+	return nil
+}
+
+// startNode 启动节点虚拟机并等待其运行，已在运行的节点跳过
+func (d *Deployer) startNode(node config.NodeSpec, log nodeLogger) error {
+	status, err := d.backend.VMStatus(node.VMID)
+	if errors.Is(err, ErrVMNotFound) {
+		log.Printf("  ⚠️  节点 %s 的虚拟机 (VM ID: %d) 不存在，跳过\n", node.Name, node.VMID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("获取虚拟机状态失败: %w", err)
+	}
+	if status.Status == "running" {
+		log.Printf("  ✓ %s 已在运行\n", node.Name)
+		return nil
+	}
+
+	log.Printf("  启动节点: %s (VM ID: %d)\n", node.Name, node.VMID)
+	if err := d.backend.StartVM(node.VMID); err != nil {
+		return fmt.Errorf("启动虚拟机失败: %w", err)
+	}
+	return d.waitVMRunning(node, log)
+}
+
+// StopNodes 按工作节点、控制平面的顺序优雅关闭选中的节点
+// 工作节点全部关闭后才关闭控制平面，工作节点关闭失败时控制平面保持运行
+func (d *Deployer) StopNodes(sel NodeSelector) error {
+	nodes, err := d.selectManagedNodes(sel)
+	if err != nil {
+		return err
+	}
+	controlPlanes, workers := splitByRole(nodes)
+	if err := d.checkStopQuorum(controlPlanes); err != nil {
+		return err
+	}
+
+	if len(workers) > 0 {
+		fmt.Println("  关闭工作节点...")
+		if err := d.forEachNode(workers, d.shutdownNode); err != nil {
+			return fmt.Errorf("关闭工作节点失败，控制平面保持运行: %w", err)
+		}
+	}
+	if len(controlPlanes) > 0 {
+		fmt.Println("  关闭控制平面...")
+		if err := d.forEachNode(controlPlanes, d.shutdownNode); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("✓ 节点停止完成")
+	return nil
+}
+
+// checkStopQuorum 只关闭部分控制平面时，确认剩余 etcd 健康的成员仍能保持 quorum
+// 关闭全部控制平面是有意停止整个集群，不做检查
+func (d *Deployer) checkStopQuorum(controlPlanes []config.NodeSpec) error {
+	all := d.config.Nodes.ControlPlanes
+	if len(controlPlanes) == 0 || len(controlPlanes) >= len(all) {
+		return nil
+	}
+	if s, err := d.State(); err != nil || !s.Bootstrapped {
+		return nil
+	}
+
+	stopping := make(map[string]bool)
+	var names []string
+	for _, node := range controlPlanes {
+		stopping[node.Name] = true
+		names = append(names, node.Name)
+	}
+	healthy := 0
+	for _, cp := range all {
+		if !stopping[cp.Name] && d.isEtcdHealthy(cp) {
+			healthy++
+		}
+	}
+	quorum := len(all)/2 + 1
+	if healthy < quorum {
+		return fmt.Errorf("关闭 %s 后 etcd 健康成员 (%d) 少于法定人数 (%d)，集群将不可用；如需停止整个集群请不指定节点运行 manage stop",
+			strings.Join(names, ", "), healthy, quorum)
+	}
+	return nil
+}
+
+// shutdownNode 优雅关闭节点
+// 已配置的节点先通过 Talos 关机（Talos 会先 cordon 并 drain 节点），
+// Talos 不可用或超时后通过 ACPI 关机，ACPI 再超时后由 Proxmox 强制停止
+func (d *Deployer) shutdownNode(node config.NodeSpec, log nodeLogger) error {
+	status, err := d.backend.VMStatus(node.VMID)
+	if errors.Is(err, ErrVMNotFound) {
+		log.Printf("  ⚠️  节点 %s 的虚拟机 (VM ID: %d) 不存在，跳过\n", node.Name, node.VMID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("获取虚拟机状态失败: %w", err)
+	}
+	if status.Status != "running" {
+		log.Printf("  ✓ %s 已停止\n", node.Name)
+		return nil
+	}
+
+	log.Printf("  关闭节点: %s (VM ID: %d)\n", node.Name, node.VMID)
+	timeout := d.config.Deploy.Timeouts.ShutdownTimeout()
+	if d.isNodeConfigured(node) {
+		_, err := d.talosOutput(30*time.Second,
+			"--nodes", node.IPAddress, "--endpoints", node.IPAddress, "shutdown", "--wait=false")
+		if err == nil {
+			err = d.waitVMStopped(node, timeout, log)
+		}
+		if err == nil {
+			return nil
+		}
+		log.Printf("    ⚠️  Talos 关机未完成，改用 ACPI: %v\n", err)
+	}
+
+	log.Printf("    ACPI 关机（%s 后强制停止）\n", timeout)
+	if err := d.backend.ShutdownVM(node.VMID, timeout); err != nil {
+		return fmt.Errorf("关闭虚拟机失败: %w", err)
+	}
+	return nil
+}
+
+// RestartNodes 逐个滚动重启选中的节点，控制平面在前
+// 每个节点重启后等待其恢复健康再处理下一个，任一节点失败立即停止
+func (d *Deployer) RestartNodes(sel NodeSelector) error {
+	nodes, err := d.selectManagedNodes(sel)
+	if err != nil {
+		return err
+	}
+	nodes = controlPlanesFirst(nodes)
+
+	// 集群健康时才开始重启，避免重启控制平面时失去 quorum
+	if s, err := d.State(); err == nil && s.Bootstrapped {
+		if err := d.waitEtcdHealthy(nodeLogger{}); err != nil {
+			return fmt.Errorf("重启前检查失败: %w", err)
+		}
+	}
+
+	for i, node := range nodes {
+		fmt.Printf("  [%d/%d] 重启节点: %s (VM ID: %d)\n", i+1, len(nodes), node.Name, node.VMID)
+		if err := d.rebootNode(node, nodeLogger{}); err != nil {
+			return fmt.Errorf("重启节点 %s 失败，已停止重启: %w", node.Name, err)
+		}
+	}
+
+	fmt.Println("✓ 节点重启完成")
+	return nil
+}
+
+// rebootNode 重启单个节点并等待其恢复健康
+// 通过 boot ID 的变化判断节点确实已重启，Talos 不可用时通过 Proxmox 关机后再启动
+func (d *Deployer) rebootNode(node config.NodeSpec, log nodeLogger) error {
+	status, err := d.backend.VMStatus(node.VMID)
+	if errors.Is(err, ErrVMNotFound) {
+		log.Printf("    ⚠️  虚拟机不存在，跳过\n")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("获取虚拟机状态失败: %w", err)
+	}
+
+	if status.Status == "running" {
+		bootID, err := d.nodeBootID(node)
+		if err == nil {
+			if _, err := d.talosOutput(30*time.Second,
+				"--nodes", node.IPAddress, "--endpoints", node.IPAddress, "reboot", "--wait=false"); err != nil {
+				return fmt.Errorf("Talos 重启失败: %w", err)
+			}
+			if err := waitFor(log, "节点重启", d.config.Deploy.Timeouts.RebootTimeout(), pollInterval, func() (bool, error) {
+				current, err := d.nodeBootID(node)
+				if err != nil {
+					return false, err
+				}
+				return current != bootID, nil
+			}); err != nil {
+				return err
+			}
+		} else {
+			log.Printf("    ⚠️  Talos API 不可用，通过 Proxmox 重启: %v\n", err)
+			if err := d.backend.ShutdownVM(node.VMID, d.config.Deploy.Timeouts.ShutdownTimeout()); err != nil {
+				return fmt.Errorf("关闭虚拟机失败: %w", err)
+			}
+			status.Status = "stopped"
+		}
+	}
+	if status.Status != "running" {
+		if err := d.backend.StartVM(node.VMID); err != nil {
+			return fmt.Errorf("启动虚拟机失败: %w", err)
+		}
+		if err := d.waitVMRunning(node, log); err != nil {
+			return err
+		}
+	}
+
+	if err := d.waitConfigured(node, log); err != nil {
+		return err
+	}
+	if node.Role == "controlplane" {
+		if err := d.waitEtcdHealthy(log); err != nil {
+			return err
+		}
+	}
+	bootID, err := d.nodeBootID(node)
+	if err != nil {
+		return fmt.Errorf("读取节点 boot ID 失败: %w", err)
+	}
+	return d.waitNodeRebooted(node, bootID, log)
+}
+
+// nodeBootID 读取节点的 boot ID，每次启动都会重新生成
+func (d *Deployer) nodeBootID(node config.NodeSpec) (string, error) {
+	out, err := d.talosOutput(15*time.Second,
+		"--nodes", node.IPAddress, "--endpoints", node.IPAddress, "read", "/proc/sys/kernel/random/boot_id")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,184 @@
+package deployer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// ResourceChange 虚拟机资源的一项变更
+type ResourceChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+func (c ResourceChange) String() string {
+	return fmt.Sprintf("%s: %s → %s", c.Field, c.From, c.To)
+}
+
+// checkOwnership 检查已存在的虚拟机是否就是该节点
+func checkOwnership(node config.NodeSpec, status *VMStatus) error {
+	if status.Template == 1 {
+		return fmt.Errorf("VM ID %d 是模板，不能用作节点 %s", node.VMID, node.Name)
+	}
+	if status.Name != node.Name {
+		return fmt.Errorf("VM ID %d 已被其他虚拟机 %q 占用", node.VMID, status.Name)
+	}
+	return nil
+}
+
+// clusterTag 返回标记集群节点的 Proxmox 标签
+// 标签只能包含小写字母、数字和 _-+.，其他字符替换为 -
+func (d *Deployer) clusterTag() string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', strings.ContainsRune("_-+.", r):
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '-'
+	}, d.config.ClusterName)
+	return "talos-cluster-" + name
+}
+
+// templateTag 返回标记节点克隆自哪个模板的 Proxmox 标签
+func templateTag(vmID int) string {
+	return fmt.Sprintf("talos-template-%d", vmID)
+}
+
+// nodeTags 返回新节点的 Proxmox 标签，用于销毁前确认归属和判断模板是否仍被使用
+func (d *Deployer) nodeTags() string {
+	return d.clusterTag() + ";" + templateTag(d.config.Proxmox.TemplateVMID)
+}
+
+// hasTag 判断 Proxmox 标签列表中是否包含 tag，标签之间以 ; , 或空格分隔
+func hasTag(tags, tag string) bool {
+	for _, t := range strings.FieldsFunc(tags, func(r rune) bool { return r == ';' || r == ',' || r == ' ' }) {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTagPrefix 判断 Proxmox 标签列表中是否有以 prefix 开头的标签
+func hasTagPrefix(tags, prefix string) bool {
+	for _, t := range strings.FieldsFunc(tags, func(r rune) bool { return r == ';' || r == ',' || r == ' ' }) {
+		if strings.HasPrefix(t, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeResourceChanges 对比节点配置与现有虚拟机，返回需要的资源变更
+// 磁盘只能扩容，配置小于现有大小时忽略
+func nodeResourceChanges(node config.NodeSpec, status *VMStatus, vmCfg map[string]string) ([]ResourceChange, error) {
+	var changes []ResourceChange
+
+	if cores := strconv.Itoa(node.CPU); vmCfg["cores"] != cores {
+		changes = append(changes, ResourceChange{Field: "cores", From: vmCfg["cores"], To: cores})
+	}
+	if memory := strconv.Itoa(node.Memory); vmCfg["memory"] != memory {
+		changes = append(changes, ResourceChange{Field: "memory", From: vmCfg["memory"], To: memory})
+	}
+
+	want, err := config.ParseSize(node.Disk)
+	if err != nil {
+		return nil, fmt.Errorf("节点 %s 的磁盘大小无效: %w", node.Name, err)
+	}
+	if want > status.MaxDisk {
+		changes = append(changes, ResourceChange{Field: "disk", From: config.FormatSize(status.MaxDisk), To: node.Disk})
+	}
+
+	return changes, nil
+}
+
+// mergeNodeTags 在现有标签后追加缺少的节点标签，返回合并后的标签和缺少的标签
+func (d *Deployer) mergeNodeTags(tags string) (string, []string) {
+	var missing []string
+	for _, tag := range strings.Split(d.nodeTags(), ";") {
+		if !hasTag(tags, tag) {
+			missing = append(missing, tag)
+		}
+	}
+	if len(missing) == 0 {
+		return tags, nil
+	}
+	if tags != "" {
+		tags += ";"
+	}
+	return tags + strings.Join(missing, ";"), missing
+}
+
+// ensureNodeTags 为被采用的已有虚拟机补充缺少的集群标签和模板标签
+// 早于标签引入创建的虚拟机没有标签，补上后 destroy 才能确认归属并识别仍在使用的模板
+func (d *Deployer) ensureNodeTags(node config.NodeSpec, status *VMStatus, log nodeLogger) error {
+	tags, missing := d.mergeNodeTags(status.Tags)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	log.Printf("    补充标签: %s\n", strings.Join(missing, ", "))
+	if err := d.backend.SetVMOptions(node.VMID, VMOptions{"tags": tags}); err != nil {
+		return fmt.Errorf("设置标签失败: %w", err)
+	}
+	return nil
+}
+
+// reconcileNode 使已存在的虚拟机与节点配置保持一致，并确保其处于运行状态
+func (d *Deployer) reconcileNode(node config.NodeSpec, status *VMStatus, log nodeLogger) error {
+	if err := checkOwnership(node, status); err != nil {
+		return err
+	}
+	if err := d.ensureNodeTags(node, status, log); err != nil {
+		return err
+	}
+
+	vmCfg, err := d.backend.VMConfig(node.VMID)
+	if err != nil {
+		return fmt.Errorf("读取虚拟机配置失败: %w", err)
+	}
+	changes, err := nodeResourceChanges(node, status, vmCfg)
+	if err != nil {
+		return err
+	}
+
+	opts := VMOptions{}
+	for _, c := range changes {
+		log.Printf("    更新 %s\n", c)
+		switch c.Field {
+		case "cores", "memory":
+			opts[c.Field] = c.To
+		case "disk":
+			if err := d.backend.ResizeDisk(node.VMID, "scsi0", node.Disk); err != nil {
+				return fmt.Errorf("调整磁盘大小失败: %w", err)
+			}
+		}
+	}
+	if len(opts) > 0 {
+		if err := d.backend.SetVMOptions(node.VMID, opts); err != nil {
+			return fmt.Errorf("配置资源失败: %w", err)
+		}
+		if status.Status == "running" {
+			log.Println("    ⚠️  CPU/内存变更需要重启虚拟机后生效")
+		}
+	}
+
+	if status.Status != "running" {
+		log.Println("    启动已停止的虚拟机")
+		if err := d.backend.StartVM(node.VMID); err != nil {
+			return fmt.Errorf("启动节点失败: %w", err)
+		}
+		return d.waitVMRunning(node, log)
+	}
+
+	if len(changes) == 0 {
+		log.Println("    ✓ 虚拟机已存在且配置一致，跳过")
+	}
+	return nil
+}
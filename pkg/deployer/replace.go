@@ -0,0 +1,163 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// etcdReplacePlan 替换控制平面时的 etcd 成员变更计划
+type etcdReplacePlan struct {
+	peer   config.NodeSpec // 用于执行 etcd 操作的健康节点
+	member *etcdMember     // 被替换节点对应的成员，已不在成员列表中时为 nil
+}
+
+// planEtcdReplace 检查替换控制平面是否会破坏 etcd quorum
+// 移除被替换节点后，剩余成员中健康的数量必须达到法定人数
+func (d *Deployer) planEtcdReplace(node config.NodeSpec) (*etcdReplacePlan, error) {
+	if len(d.config.Nodes.ControlPlanes) < 2 {
+		return nil, fmt.Errorf("集群只有一个控制平面，替换会丢失全部 etcd 数据，请重建节点后使用 etcd restore 恢复")
+	}
+
+	var healthy []config.NodeSpec
+	for _, cp := range d.config.Nodes.ControlPlanes {
+		if cp.Name != node.Name && d.isEtcdHealthy(cp) {
+			healthy = append(healthy, cp)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil, fmt.Errorf("除 %s 外没有 etcd 健康的控制平面，无法安全替换，请使用 etcd restore 恢复", node.Name)
+	}
+
+	members, err := d.etcdMembers(healthy[0])
+	if err != nil {
+		return nil, err
+	}
+	plan := &etcdReplacePlan{peer: healthy[0]}
+	for i := range members {
+		if members[i].Hostname == node.Name {
+			plan.member = &members[i]
+			break
+		}
+	}
+
+	remaining := len(members)
+	if plan.member != nil {
+		remaining--
+	}
+	quorum := remaining/2 + 1
+	fmt.Printf("  etcd 成员: %d 个，移除 %s 后剩余 %d 个，健康 %d 个（法定人数 %d）\n",
+		len(members), node.Name, remaining, len(healthy), quorum)
+	if len(healthy) < quorum {
+		return nil, fmt.Errorf("移除 %s 后健康的 etcd 成员 (%d) 少于法定人数 (%d)，替换会破坏 quorum，请先恢复其他控制平面",
+			node.Name, len(healthy), quorum)
+	}
+	// 新成员加入后、启动完成前，成员数增加但健康数不变
+	if len(healthy) < (remaining+1)/2+1 {
+		fmt.Printf("  ⚠️  新节点加入 etcd 到启动完成期间集群暂时没有 quorum，API 会短暂不可用\n")
+	}
+	return plan, nil
+}
+
+// ReplaceNode 替换故障节点：销毁虚拟机后从模板重新克隆，应用该节点的配置并等待其重新加入集群
+// 控制平面会先通过健康的 etcd 成员移除旧成员，移除会破坏 quorum 时拒绝执行
+func (d *Deployer) ReplaceNode(name string) error {
+	node, ok := d.config.FindNode(name)
+	if !ok {
+		return fmt.Errorf("未找到节点: %s", name)
+	}
+	if s, err := d.State(); err == nil {
+		if recorded, ok := s.Lookup(name); ok && recorded.VMID != 0 {
+			node.VMID = recorded.VMID
+		}
+	}
+
+	fmt.Printf("🔁 替换节点: %s (VM ID: %d)\n", node.Name, node.VMID)
+
+	status, err := d.backend.VMStatus(node.VMID)
+	vmExists := err == nil
+	if vmExists {
+		if err := d.checkNodeOwnership(node, status); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, ErrVMNotFound) {
+		return fmt.Errorf("检查虚拟机状态失败: %w", err)
+	}
+
+	var plan *etcdReplacePlan
+	if node.Role == "controlplane" {
+		if plan, err = d.planEtcdReplace(node); err != nil {
+			return err
+		}
+	}
+
+	// 移除 etcd 成员前不做任何破坏性操作，失败时集群保持原样
+	if plan != nil && plan.member != nil {
+		fmt.Printf("  从 etcd 移除成员 %s (%s)，通过 %s\n", plan.member.Hostname, plan.member.ID, plan.peer.Name)
+		out, err := d.talosctl("etcd", "remove-member", plan.member.ID,
+			"--nodes", plan.peer.IPAddress,
+			"--endpoints", plan.peer.IPAddress,
+		).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("移除 etcd 成员失败: %w: %s", err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	if vmExists {
+		fmt.Println("  销毁虚拟机...")
+		if status.Status == "running" {
+			if err := d.backend.StopVM(node.VMID); err != nil {
+				return fmt.Errorf("停止虚拟机失败: %w", err)
+			}
+		}
+		if err := d.backend.DestroyVM(node.VMID); err != nil {
+			return fmt.Errorf("删除虚拟机失败: %w", err)
+		}
+	}
+	if err := d.deleteKubeNode(node.Name); err != nil {
+		fmt.Printf("  ⚠️  %v\n", err)
+	}
+	if err := d.recordNodeRemoved(node.Name); err != nil {
+		return err
+	}
+
+	// 节点配置基于已有的角色配置重新生成，与集群其他节点使用相同的 secrets
+	if err := os.MkdirAll(d.nodesDir(), 0755); err != nil {
+		return fmt.Errorf("创建节点配置目录失败: %w", err)
+	}
+	if err := d.renderNodeConfig(d.config.ConfigDir(), node); err != nil {
+		return err
+	}
+	if err := d.recordConfigHashes(); err != nil {
+		return err
+	}
+
+	log := nodeLogger{}
+	if err := d.createNode(node, log); err != nil {
+		return fmt.Errorf("创建节点 %s 失败: %w", node.Name, err)
+	}
+	if err := d.recordNodeCreated(node); err != nil {
+		return err
+	}
+	if err := d.configureNode(node, log); err != nil {
+		return err
+	}
+
+	if plan != nil {
+		if err := d.waitEtcdJoined(node, plan.peer, log); err != nil {
+			return err
+		}
+		if err := d.waitEtcdHealthy(log); err != nil {
+			return err
+		}
+	}
+	if err := d.waitNodeReady(node, log); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ 节点 %s 已替换\n", node.Name)
+	return nil
+}
@@ -0,0 +1,80 @@
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecretsPath 返回集群 Talos secrets bundle 路径
+func (d *Deployer) SecretsPath() string {
+	return filepath.Join(d.config.ConfigDir(), "secrets.yaml")
+}
+
+// ensureSecrets 确保 secrets bundle 存在，仅在首次部署时生成
+func (d *Deployer) ensureSecrets() error {
+	path := d.SecretsPath()
+	if _, err := os.Stat(path); err == nil {
+		fmt.Printf("  使用已有 secrets: %s\n", path)
+		return nil
+	}
+
+	fmt.Println("  生成 secrets bundle...")
+	cmd := d.command("talosctl", "gen", "secrets", "--output-file", path)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("生成 secrets 失败: %w: %s", err, string(out))
+	}
+	return os.Chmod(path, 0600)
+}
+
+// validateSecrets 检查文件是否为 Talos secrets bundle
+func validateSecrets(data []byte) error {
+	var bundle map[string]interface{}
+	if err := yaml.Unmarshal(data, &bundle); err != nil {
+		return fmt.Errorf("解析 secrets 失败: %w", err)
+	}
+	for _, key := range []string{"cluster", "secrets", "certs"} {
+		if _, ok := bundle[key]; !ok {
+			return fmt.Errorf("不是有效的 Talos secrets bundle: 缺少 %s 字段", key)
+		}
+	}
+	return nil
+}
+
+// ExportSecrets 将 secrets bundle 导出到 dst，用于备份
+func (d *Deployer) ExportSecrets(dst string) error {
+	data, err := os.ReadFile(d.SecretsPath())
+	if err != nil {
+		return fmt.Errorf("读取 secrets 失败: %w", err)
+	}
+	if err := os.WriteFile(dst, data, 0600); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", dst, err)
+	}
+	return nil
+}
+
+// ImportSecrets 从备份导入 secrets bundle，已存在时需要 force 才会覆盖
+func (d *Deployer) ImportSecrets(src string, force bool) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("读取 %s 失败: %w", src, err)
+	}
+	if err := validateSecrets(data); err != nil {
+		return err
+	}
+
+	path := d.SecretsPath()
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("secrets 已存在: %s，使用 --force 覆盖", path)
+	}
+
+	if err := os.MkdirAll(d.config.ConfigDir(), 0755); err != nil {
+		return fmt.Errorf("创建配置目录失败: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("写入 secrets 失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package deployer
+
+import (
+	"fmt"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// NodeSelector 按名称、角色和标签选择节点
+// 各条件需同时满足，未设置的条件不做限制
+type NodeSelector struct {
+	Names  []string          // 节点名称
+	Role   string            // controlplane 或 worker
+	Labels map[string]string // 节点标签，需全部匹配
+}
+
+// IsEmpty 判断是否未设置任何条件，即选择所有节点
+func (s NodeSelector) IsEmpty() bool {
+	return len(s.Names) == 0 && s.Role == "" && len(s.Labels) == 0
+}
+
+// matches 判断节点是否满足所有条件
+func (s NodeSelector) matches(node config.NodeSpec) bool {
+	if len(s.Names) > 0 {
+		found := false
+		for _, name := range s.Names {
+			if name == node.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if s.Role != "" && node.Role != s.Role {
+		return false
+	}
+	for k, v := range s.Labels {
+		if node.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// selectManagedNodes 返回应管理的节点中满足选择条件的节点
+func (d *Deployer) selectManagedNodes(sel NodeSelector) ([]config.NodeSpec, error) {
+	if sel.Role != "" && sel.Role != "controlplane" && sel.Role != "worker" {
+		return nil, fmt.Errorf("无效的节点角色: %s，必须是 'controlplane' 或 'worker'", sel.Role)
+	}
+
+	nodes, err := d.managedNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+	for _, node := range nodes {
+		known[node.Name] = true
+	}
+	for _, name := range sel.Names {
+		if !known[name] {
+			return nil, fmt.Errorf("未找到节点: %s", name)
+		}
+	}
+
+	var selected []config.NodeSpec
+	for _, node := range nodes {
+		if sel.matches(node) {
+			selected = append(selected, node)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("没有匹配的节点")
+	}
+	return selected, nil
+}
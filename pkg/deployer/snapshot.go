@@ -0,0 +1,298 @@
+package deployer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// quiesceTimeout 快照前优雅关机的超时，超时后强制停止
+const quiesceTimeout = 3 * time.Minute
+
+// snapshotNamePattern Proxmox 快照名规则：字母开头，只含字母、数字、- 和 _，最长 40 个字符
+var snapshotNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,39}$`)
+
+// ValidateSnapshotName 检查快照名是否符合 Proxmox 要求
+func ValidateSnapshotName(name string) error {
+	if !snapshotNamePattern.MatchString(name) {
+		return fmt.Errorf("无效的快照名 %q：必须以字母开头，只含字母、数字、- 和 _，最长 40 个字符", name)
+	}
+	return nil
+}
+
+// preUpgradeSnapshotName 返回升级前自动快照的名称
+func preUpgradeSnapshotName() string {
+	return "pre-upgrade-" + time.Now().Format("20060102-150405")
+}
+
+// ClusterSnapshot 集群中各节点上的同名快照
+type ClusterSnapshot struct {
+	Name        string    `json:"name" yaml:"name"`
+	Description string    `json:"description,omitempty" yaml:"description,omitempty"`
+	Time        time.Time `json:"time" yaml:"time"`
+	Nodes       []string  `json:"nodes" yaml:"nodes"`     // 拥有该快照的节点
+	Missing     []string  `json:"missing" yaml:"missing"` // 缺少该快照的节点
+}
+
+// Complete 判断快照是否覆盖所有节点，只有完整的快照才能回滚
+func (s ClusterSnapshot) Complete() bool {
+	return len(s.Missing) == 0
+}
+
+// stopOrder 返回关机顺序：工作节点在前，控制平面最后
+func stopOrder(nodes []config.NodeSpec) []config.NodeSpec {
+	sorted := controlPlanesFirst(nodes)
+	for i, j := 0, len(sorted)-1; i < j; i, j = i+1, j-1 {
+		sorted[i], sorted[j] = sorted[j], sorted[i]
+	}
+	return sorted
+}
+
+// nodeSnapshots 返回每个节点拥有的快照名
+func (d *Deployer) nodeSnapshots(nodes []config.NodeSpec) (map[string]map[string]Snapshot, error) {
+	result := make(map[string]map[string]Snapshot, len(nodes))
+	for _, node := range nodes {
+		snapshots, err := d.backend.ListSnapshots(node.VMID)
+		if err != nil {
+			return nil, fmt.Errorf("获取节点 %s 的快照失败: %w", node.Name, err)
+		}
+		byName := make(map[string]Snapshot, len(snapshots))
+		for _, s := range snapshots {
+			byName[s.Name] = s
+		}
+		result[node.Name] = byName
+	}
+	return result, nil
+}
+
+// CreateSnapshot 为所有节点虚拟机创建同名快照
+// quiesce 为 true 时先按工作节点、控制平面的顺序优雅关机，快照完成后按相反顺序启动
+// 任一节点失败时删除已在其他节点上创建的快照，保证快照集一致
+func (d *Deployer) CreateSnapshot(name string, quiesce bool) error {
+	if err := ValidateSnapshotName(name); err != nil {
+		return err
+	}
+	nodes := d.config.AllNodes()
+	existing, err := d.nodeSnapshots(nodes)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if _, ok := existing[node.Name][name]; ok {
+			return fmt.Errorf("节点 %s 上已存在快照 %s", node.Name, name)
+		}
+	}
+
+	fmt.Printf("📸 创建集群快照: %s\n", name)
+
+	var restart []config.NodeSpec
+	if quiesce {
+		restart, err = d.quiesceNodes(nodes)
+		if err != nil {
+			return err
+		}
+	}
+
+	description := fmt.Sprintf("talos-deployer: %s, Talos %s", d.config.ClusterName, d.config.TalosVersion)
+	if d.config.KubernetesVersion != "" {
+		description += ", Kubernetes " + d.config.KubernetesVersion
+	}
+
+	var snapErr error
+	var created []config.NodeSpec
+	for _, node := range nodes {
+		fmt.Printf("  创建快照: %s (VM ID: %d)\n", node.Name, node.VMID)
+		if err := d.backend.CreateSnapshot(node.VMID, name, description, false); err != nil {
+			snapErr = fmt.Errorf("节点 %s 创建快照失败: %w", node.Name, err)
+			break
+		}
+		created = append(created, node)
+	}
+
+	if snapErr != nil && len(created) > 0 {
+		fmt.Println("  删除已创建的快照...")
+		for _, node := range created {
+			if err := d.backend.DeleteSnapshot(node.VMID, name); err != nil {
+				fmt.Printf("  ⚠️  删除节点 %s 的快照失败: %v\n", node.Name, err)
+			}
+		}
+	}
+
+	// 无论快照是否成功，都恢复关机前在运行的节点
+	if err := d.startInOrder(restart); err != nil {
+		if snapErr != nil {
+			return fmt.Errorf("%w；恢复节点失败: %v", snapErr, err)
+		}
+		return err
+	}
+	if snapErr != nil {
+		return snapErr
+	}
+
+	fmt.Printf("✓ 已为 %d 个节点创建快照 %s\n", len(nodes), name)
+	return nil
+}
+
+// quiesceNodes 按工作节点、控制平面的顺序优雅关闭运行中的节点，返回被关闭的节点
+func (d *Deployer) quiesceNodes(nodes []config.NodeSpec) ([]config.NodeSpec, error) {
+	var stopped []config.NodeSpec
+	for _, node := range stopOrder(nodes) {
+		status, err := d.backend.VMStatus(node.VMID)
+		if err != nil {
+			return stopped, fmt.Errorf("获取节点 %s 状态失败: %w", node.Name, err)
+		}
+		if status.Status != "running" {
+			continue
+		}
+
+		fmt.Printf("  关闭节点: %s (VM ID: %d)\n", node.Name, node.VMID)
+		if err := d.backend.ShutdownVM(node.VMID, quiesceTimeout); err != nil {
+			return stopped, fmt.Errorf("关闭节点 %s 失败: %w", node.Name, err)
+		}
+		stopped = append(stopped, node)
+	}
+	return stopped, nil
+}
+
+// startInOrder 按控制平面、工作节点的顺序启动节点并等待其运行
+func (d *Deployer) startInOrder(nodes []config.NodeSpec) error {
+	for _, node := range controlPlanesFirst(nodes) {
+		fmt.Printf("  启动节点: %s (VM ID: %d)\n", node.Name, node.VMID)
+		if err := d.backend.StartVM(node.VMID); err != nil {
+			return fmt.Errorf("启动节点 %s 失败: %w", node.Name, err)
+		}
+		if err := d.waitVMRunning(node, nodeLogger{}); err != nil {
+			return fmt.Errorf("节点 %s 未启动: %w", node.Name, err)
+		}
+	}
+	return nil
+}
+
+// ListSnapshots 按名称汇总所有节点上的快照，按创建时间排序
+func (d *Deployer) ListSnapshots() ([]ClusterSnapshot, error) {
+	nodes := d.config.AllNodes()
+	perNode, err := d.nodeSnapshots(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*ClusterSnapshot)
+	for _, node := range nodes {
+		for name, s := range perNode[node.Name] {
+			cs, ok := byName[name]
+			if !ok {
+				cs = &ClusterSnapshot{Name: name, Description: s.Description, Time: time.Unix(s.SnapTime, 0)}
+				byName[name] = cs
+			}
+			// 以最早的节点快照时间为准
+			if t := time.Unix(s.SnapTime, 0); t.Before(cs.Time) {
+				cs.Time = t
+			}
+			cs.Nodes = append(cs.Nodes, node.Name)
+		}
+	}
+
+	snapshots := make([]ClusterSnapshot, 0, len(byName))
+	for _, cs := range byName {
+		for _, node := range nodes {
+			if _, ok := perNode[node.Name][cs.Name]; !ok {
+				cs.Missing = append(cs.Missing, node.Name)
+			}
+		}
+		snapshots = append(snapshots, *cs)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Time.Before(snapshots[j].Time) })
+	return snapshots, nil
+}
+
+// RollbackSnapshot 将所有节点回滚到同名快照
+// 先确认每个节点都有该快照，再停止全部节点、逐个回滚，全部成功后按控制平面优先的顺序启动
+func (d *Deployer) RollbackSnapshot(name string) error {
+	nodes := d.config.AllNodes()
+	perNode, err := d.nodeSnapshots(nodes)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, node := range nodes {
+		if _, ok := perNode[node.Name][name]; !ok {
+			missing = append(missing, node.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("快照 %s 在节点 %s 上不存在，无法一致回滚", name, strings.Join(missing, ", "))
+	}
+
+	fmt.Printf("⏪ 回滚集群到快照: %s\n", name)
+
+	// 先停止所有节点，避免回滚过程中新旧状态的节点互相通信
+	for _, node := range stopOrder(nodes) {
+		status, err := d.backend.VMStatus(node.VMID)
+		if err != nil {
+			return fmt.Errorf("获取节点 %s 状态失败: %w", node.Name, err)
+		}
+		if status.Status == "running" {
+			fmt.Printf("  停止节点: %s (VM ID: %d)\n", node.Name, node.VMID)
+			if err := d.backend.StopVM(node.VMID); err != nil {
+				return fmt.Errorf("停止节点 %s 失败: %w", node.Name, err)
+			}
+		}
+	}
+
+	for _, node := range nodes {
+		fmt.Printf("  回滚节点: %s (VM ID: %d)\n", node.Name, node.VMID)
+		if err := d.backend.RollbackSnapshot(node.VMID, name); err != nil {
+			return fmt.Errorf("回滚节点 %s 失败，所有节点保持停止状态，修复后可重新执行回滚: %w", node.Name, err)
+		}
+	}
+
+	if err := d.startInOrder(nodes); err != nil {
+		return err
+	}
+
+	if s := perNode[nodes[0].Name][name]; s.Description != "" {
+		fmt.Printf("  快照说明: %s\n", s.Description)
+	}
+	fmt.Printf("✓ 集群已回滚到快照 %s\n", name)
+	fmt.Println("  如果快照早于升级，请同步修改配置中的 talos_version / kubernetes_version")
+	return nil
+}
+
+// DeleteSnapshot 删除所有节点上的同名快照，单个节点失败不影响其他节点
+func (d *Deployer) DeleteSnapshot(name string) error {
+	nodes := d.config.AllNodes()
+	perNode, err := d.nodeSnapshots(nodes)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🗑️  删除集群快照: %s\n", name)
+
+	var found int
+	var failed []string
+	for _, node := range nodes {
+		if _, ok := perNode[node.Name][name]; !ok {
+			continue
+		}
+		found++
+		fmt.Printf("  删除快照: %s (VM ID: %d)\n", node.Name, node.VMID)
+		if err := d.backend.DeleteSnapshot(node.VMID, name); err != nil {
+			fmt.Printf("  ⚠️  删除节点 %s 的快照失败: %v\n", node.Name, err)
+			failed = append(failed, node.Name)
+		}
+	}
+
+	if found == 0 {
+		return fmt.Errorf("快照 %s 不存在", name)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("节点 %s 的快照删除失败", strings.Join(failed, ", "))
+	}
+	fmt.Printf("✓ 已删除 %d 个节点上的快照 %s\n", found, name)
+	return nil
+}
@@ -0,0 +1,284 @@
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"talos-proxmox-deployer/pkg/config"
+	"talos-proxmox-deployer/pkg/state"
+)
+
+// State 返回集群部署状态，首次调用时从配置目录加载
+func (d *Deployer) State() (*state.State, error) {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+
+	if d.state != nil {
+		return d.state, nil
+	}
+
+	s, err := state.Load(state.Path(d.config.ConfigDir()))
+	if err != nil {
+		return nil, err
+	}
+	d.state = s
+	return s, nil
+}
+
+// updateState 修改并保存部署状态
+func (d *Deployer) updateState(fn func(s *state.State)) error {
+	s, err := d.State()
+	if err != nil {
+		return err
+	}
+	if err := s.Update(func(s *state.State) {
+		s.ClusterName = d.config.ClusterName
+		fn(s)
+	}); err != nil {
+		return fmt.Errorf("保存部署状态失败: %w", err)
+	}
+	return nil
+}
+
+// parseMAC 从 net0 配置（如 "virtio=BC:24:11:00:00:01,bridge=vmbr0"）中提取 MAC 地址
+func parseMAC(net0 string) string {
+	model, _, _ := strings.Cut(net0, ",")
+	_, mac, ok := strings.Cut(model, "=")
+	if !ok {
+		return ""
+	}
+	return strings.ToUpper(mac)
+}
+
+// recordTemplate 记录已创建的模板
+func (d *Deployer) recordTemplate() error {
+	return d.updateState(func(s *state.State) {
+		s.TemplateVMID = d.config.Proxmox.TemplateVMID
+		s.TalosVersion = d.config.TalosVersion
+	})
+}
+
+// recordNode 记录节点虚拟机的 VMID、MAC 和所在 Proxmox 节点
+func (d *Deployer) recordNode(node config.NodeSpec) error {
+	vmCfg, err := d.backend.VMConfig(node.VMID)
+	if err != nil {
+		return fmt.Errorf("读取虚拟机配置失败: %w", err)
+	}
+	pveNode, err := d.backend.NodeName()
+	if err != nil {
+		return fmt.Errorf("获取 Proxmox 节点名失败: %w", err)
+	}
+
+	return d.updateState(func(s *state.State) {
+		n := s.Node(node.Name)
+		if n.VMID != node.VMID {
+			// 新虚拟机，之前的状态不再有效
+			n.Created = false
+			n.Configured = false
+		}
+		n.VMID = node.VMID
+		n.Role = node.Role
+		n.IPAddress = node.IPAddress
+		n.MAC = parseMAC(vmCfg["net0"])
+		n.PVENode = pveNode
+	})
+}
+
+// recordNodeCreated 记录节点虚拟机已创建并启动
+func (d *Deployer) recordNodeCreated(node config.NodeSpec) error {
+	return d.updateState(func(s *state.State) {
+		s.Node(node.Name).Created = true
+	})
+}
+
+// recordConfigHashes 记录生成的配置文件哈希
+func (d *Deployer) recordConfigHashes() error {
+	configDir := d.config.ConfigDir()
+	files := []string{"secrets.yaml", "controlplane.yaml", "worker.yaml", "talosconfig"}
+
+	hashes := make(map[string]string)
+	for _, f := range files {
+		h, err := state.HashFile(filepath.Join(configDir, f))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("计算 %s 哈希失败: %w", f, err)
+		}
+		hashes[f] = h
+	}
+
+	nodeHashes := make(map[string]string)
+	inputHashes := make(map[string]string)
+	for _, node := range d.config.AllNodes() {
+		h, err := state.HashFile(d.nodeConfigPath(node))
+		if err != nil {
+			return fmt.Errorf("计算节点 %s 配置哈希失败: %w", node.Name, err)
+		}
+		rel, _ := filepath.Rel(configDir, d.nodeConfigPath(node))
+		hashes[rel] = h
+		nodeHashes[node.Name] = h
+
+		if inputHashes[node.Name], err = d.nodeConfigInputHash(node); err != nil {
+			return err
+		}
+	}
+
+	return d.updateState(func(s *state.State) {
+		s.ConfigHashes = hashes
+		for name, h := range nodeHashes {
+			if n, ok := s.Nodes[name]; ok {
+				n.ConfigHash = h
+				n.InputHash = inputHashes[name]
+			}
+		}
+	})
+}
+
+// recordNodeConfigured 记录节点已应用配置
+func (d *Deployer) recordNodeConfigured(node config.NodeSpec) error {
+	hash, _ := state.HashFile(d.nodeConfigPath(node))
+	inputHash, _ := d.nodeConfigInputHash(node)
+	return d.updateState(func(s *state.State) {
+		n := s.Node(node.Name)
+		n.VMID = node.VMID
+		n.Role = node.Role
+		n.IPAddress = node.IPAddress
+		n.Configured = true
+		n.TalosVersion = d.config.TalosVersion
+		if hash != "" {
+			n.ConfigHash = hash
+		}
+		if inputHash != "" {
+			n.InputHash = inputHash
+		}
+	})
+}
+
+// recordNodeRemoved 从部署状态中删除已移除的节点
+func (d *Deployer) recordNodeRemoved(name string) error {
+	return d.updateState(func(s *state.State) {
+		delete(s.Nodes, name)
+	})
+}
+
+// recordBootstrapped 记录 etcd 已引导
+func (d *Deployer) recordBootstrapped() error {
+	return d.updateState(func(s *state.State) {
+		s.Bootstrapped = true
+	})
+}
+
+// recordNodeTalosVersion 记录节点升级后运行的 Talos 版本
+func (d *Deployer) recordNodeTalosVersion(node config.NodeSpec, version string) error {
+	return d.updateState(func(s *state.State) {
+		s.Node(node.Name).TalosVersion = version
+	})
+}
+
+// recordTalosVersion 记录集群整体已升级到的 Talos 版本
+func (d *Deployer) recordTalosVersion(version string) error {
+	return d.updateState(func(s *state.State) {
+		s.TalosVersion = version
+	})
+}
+
+// TrackedNodes 合并配置和部署状态，返回实际应管理的节点
+// 状态中记录的 VMID 优先于配置；已从配置中移除但仍记录在状态中的节点也会返回
+func (d *Deployer) TrackedNodes() ([]config.NodeSpec, []string, error) {
+	s, err := d.State()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var nodes []config.NodeSpec
+	var warnings []string
+	seen := make(map[string]bool)
+
+	for _, node := range d.config.AllNodes() {
+		seen[node.Name] = true
+		if recorded, ok := s.Lookup(node.Name); ok && recorded.VMID != node.VMID {
+			warnings = append(warnings, fmt.Sprintf("节点 %s 的 VM ID 在配置中为 %d，但实际创建的是 %d，使用 %d",
+				node.Name, node.VMID, recorded.VMID, recorded.VMID))
+			node.VMID = recorded.VMID
+		}
+		nodes = append(nodes, node)
+	}
+
+	for _, name := range s.NodeNames() {
+		if seen[name] {
+			continue
+		}
+		recorded, _ := s.Lookup(name)
+		warnings = append(warnings, fmt.Sprintf("节点 %s (VM ID: %d) 已从配置中移除，但仍记录在部署状态中", name, recorded.VMID))
+		nodes = append(nodes, config.NodeSpec{
+			Name:      name,
+			VMID:      recorded.VMID,
+			IPAddress: recorded.IPAddress,
+			Role:      recorded.Role,
+		})
+	}
+
+	return nodes, warnings, nil
+}
+
+// templateVMID 返回实际创建的模板 VMID，没有记录时使用配置
+func (d *Deployer) templateVMID() int {
+	if s, err := d.State(); err == nil && s.TemplateVMID != 0 {
+		return s.TemplateVMID
+	}
+	return d.config.Proxmox.TemplateVMID
+}
+
+// managedNodes 返回应管理的节点，并打印配置与部署状态不一致的警告
+func (d *Deployer) managedNodes() ([]config.NodeSpec, error) {
+	nodes, warnings, err := d.TrackedNodes()
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range warnings {
+		fmt.Printf("  ⚠️  %s\n", w)
+	}
+	return nodes, nil
+}
+
+// checkState 对比部署状态与配置并打印结果
+func (d *Deployer) checkState() error {
+	s, err := d.State()
+	if err != nil {
+		return err
+	}
+	if !s.Exists() {
+		fmt.Println("⚠️  未找到部署状态文件，集群可能不是由本工具部署的")
+		return nil
+	}
+
+	_, warnings, err := d.TrackedNodes()
+	if err != nil {
+		return err
+	}
+	for _, name := range s.NodeNames() {
+		if n, _ := s.Lookup(name); !n.Configured {
+			warnings = append(warnings, fmt.Sprintf("节点 %s 尚未应用配置", name))
+		}
+	}
+	for _, node := range d.config.AllNodes() {
+		if _, ok := s.Lookup(node.Name); !ok {
+			warnings = append(warnings, fmt.Sprintf("节点 %s 在配置中，但尚未创建", node.Name))
+		}
+	}
+	if !s.Bootstrapped {
+		warnings = append(warnings, "etcd 尚未引导")
+	}
+
+	if len(warnings) == 0 {
+		fmt.Printf("✓ 部署状态与配置一致（%d 个节点）\n", len(s.Nodes))
+		return nil
+	}
+	for _, w := range warnings {
+		fmt.Printf("⚠️  %s\n", w)
+	}
+	return nil
+}
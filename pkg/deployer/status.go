@@ -0,0 +1,242 @@
+package deployer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"talos-proxmox-deployer/pkg/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NodeStatus 汇总单个节点在 Proxmox、Talos 和 Kubernetes 中的状态
+// 无法获取的字段留空
+type NodeStatus struct {
+	Name      string `yaml:"name" json:"name"`
+	Role      string `yaml:"role" json:"role"`
+	IPAddress string `yaml:"ip_address" json:"ip_address"`
+
+	// Proxmox
+	VMID     int     `yaml:"vm_id" json:"vm_id"`
+	PVENode  string  `yaml:"pve_node,omitempty" json:"pve_node,omitempty"`
+	Power    string  `yaml:"power" json:"power"` // running、stopped、missing 或 unknown
+	CPUs     int     `yaml:"cpus,omitempty" json:"cpus,omitempty"`
+	CPUUsage float64 `yaml:"cpu_usage" json:"cpu_usage"` // CPU 使用率（0-1）
+	MemUsed  int64   `yaml:"mem_used" json:"mem_used"`   // 字节
+	MemTotal int64   `yaml:"mem_total" json:"mem_total"` // 字节
+
+	// Talos
+	TalosVersion string `yaml:"talos_version,omitempty" json:"talos_version,omitempty"`
+	TalosStage   string `yaml:"talos_stage" json:"talos_stage"` // maintenance、booting、running 等，不可达时为 unreachable
+
+	// etcd，仅控制平面
+	Etcd string `yaml:"etcd,omitempty" json:"etcd,omitempty"` // member、learner、absent 或 unknown
+
+	// Kubernetes
+	KubeReady      string `yaml:"kube_ready" json:"kube_ready"` // Ready、NotReady、absent 或 unknown
+	KubeletVersion string `yaml:"kubelet_version,omitempty" json:"kubelet_version,omitempty"`
+}
+
+// kubeNodeInfo Kubernetes 节点的就绪状态和 kubelet 版本
+type kubeNodeInfo struct {
+	ready          string
+	kubeletVersion string
+}
+
+// NodeStatuses 返回配置中每个节点的状态，VM ID 以部署状态中记录的为准
+// 各节点的 Proxmox 和 Talos 状态并发获取，etcd 成员和 Kubernetes 节点各查询一次
+func (d *Deployer) NodeStatuses() ([]NodeStatus, error) {
+	s, err := d.State()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := d.config.AllNodes()
+	statuses := make([]NodeStatus, len(nodes))
+	for i, node := range nodes {
+		st := NodeStatus{Name: node.Name, Role: node.Role, IPAddress: node.IPAddress, VMID: node.VMID}
+		if recorded, ok := s.Lookup(node.Name); ok {
+			if recorded.VMID != 0 {
+				st.VMID = recorded.VMID
+			}
+			st.PVENode = recorded.PVENode
+		}
+		statuses[i] = st
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(nodes))
+	for i := range statuses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = d.fillNodeStatus(&statuses[i])
+		}(i)
+	}
+
+	var members map[string]etcdMember
+	var kubeNodes map[string]kubeNodeInfo
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		members = d.etcdMemberMap()
+	}()
+	go func() {
+		defer wg.Done()
+		kubeNodes = d.kubeNodeInfos()
+	}()
+	wg.Wait()
+
+	for i := range statuses {
+		// 单个节点查询失败时该行显示 unknown，警告写到标准错误，不影响 -o json 输出
+		if errs[i] != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v\n", errs[i])
+		}
+		st := &statuses[i]
+
+		if st.Role == "controlplane" {
+			switch m, ok := members[st.Name]; {
+			case members == nil:
+				st.Etcd = "unknown"
+			case !ok:
+				st.Etcd = "absent"
+			case m.Learner:
+				st.Etcd = "learner"
+			default:
+				st.Etcd = "member"
+			}
+		}
+
+		switch info, ok := kubeNodes[st.Name]; {
+		case kubeNodes == nil:
+			st.KubeReady = "unknown"
+		case !ok:
+			st.KubeReady = "absent"
+		default:
+			st.KubeReady = info.ready
+			st.KubeletVersion = info.kubeletVersion
+		}
+	}
+	return statuses, nil
+}
+
+// fillNodeStatus 填充节点的 Proxmox 和 Talos 状态
+// 无法获取虚拟机状态时 Power 和 TalosStage 为 unknown，并返回错误供调用方输出警告
+func (d *Deployer) fillNodeStatus(st *NodeStatus) error {
+	vm, err := d.backend.VMStatus(st.VMID)
+	if errors.Is(err, ErrVMNotFound) {
+		st.Power = "missing"
+		st.TalosStage = "unreachable"
+		return nil
+	}
+	if err != nil {
+		st.Power = "unknown"
+		st.TalosStage = "unknown"
+		return fmt.Errorf("获取节点 %s 的虚拟机状态失败: %w", st.Name, err)
+	}
+	st.Power = vm.Status
+	st.CPUs = vm.CPUs
+	st.CPUUsage = vm.CPU
+	st.MemUsed = vm.Mem
+	st.MemTotal = vm.MaxMem
+	if st.PVENode == "" {
+		st.PVENode, _ = d.backend.NodeName()
+	}
+
+	if vm.Status != "running" {
+		st.TalosStage = "unreachable"
+		return nil
+	}
+	st.TalosVersion, st.TalosStage = d.talosStage(config.NodeSpec{Name: st.Name, IPAddress: st.IPAddress})
+	return nil
+}
+
+// talosStage 返回节点的 Talos 版本和启动阶段
+// 已配置的节点读取 MachineStatus 资源，维护模式的节点只接受 --insecure 请求
+func (d *Deployer) talosStage(node config.NodeSpec) (version, stage string) {
+	if v, err := d.NodeTalosVersion(node); err == nil {
+		out, err := d.talosOutput(15*time.Second,
+			"--nodes", node.IPAddress, "--endpoints", node.IPAddress, "get", "machinestatus", "-o", "json")
+		if err != nil {
+			return v, "unknown"
+		}
+		var status struct {
+			Spec struct {
+				Stage string `json:"stage"`
+			} `json:"spec"`
+		}
+		if err := json.Unmarshal(out, &status); err != nil || status.Spec.Stage == "" {
+			return v, "unknown"
+		}
+		return v, status.Spec.Stage
+	}
+
+	out, err := d.talosOutput(15*time.Second,
+		"--nodes", node.IPAddress, "--endpoints", node.IPAddress, "version", "--insecure")
+	if err != nil {
+		return "", "unreachable"
+	}
+	return parseServerTag(string(out)), "maintenance"
+}
+
+// etcdMemberMap 通过第一个 etcd 健康的控制平面获取成员列表，按主机名索引
+// 没有健康的控制平面时返回 nil
+func (d *Deployer) etcdMemberMap() map[string]etcdMember {
+	peer, err := d.firstHealthyControlPlane()
+	if err != nil {
+		return nil
+	}
+	members, err := d.etcdMembers(peer)
+	if err != nil {
+		return nil
+	}
+	m := make(map[string]etcdMember, len(members))
+	for _, member := range members {
+		m[member.Hostname] = member
+	}
+	return m
+}
+
+// kubeNodeInfos 获取 Kubernetes 中所有节点的就绪状态和 kubelet 版本，按节点名索引
+// Kubernetes API 不可用时返回 nil
+func (d *Deployer) kubeNodeInfos() map[string]kubeNodeInfo {
+	out, err := d.kubectl("get", "nodes", "-o", "yaml", "--request-timeout", "10s").Output()
+	if err != nil {
+		return nil
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `yaml:"name"`
+			} `yaml:"metadata"`
+			Status struct {
+				Conditions []struct {
+					Type   string `yaml:"type"`
+					Status string `yaml:"status"`
+				} `yaml:"conditions"`
+				NodeInfo struct {
+					KubeletVersion string `yaml:"kubeletVersion"`
+				} `yaml:"nodeInfo"`
+			} `yaml:"status"`
+		} `yaml:"items"`
+	}
+	if err := yaml.Unmarshal(out, &list); err != nil {
+		return nil
+	}
+
+	infos := make(map[string]kubeNodeInfo, len(list.Items))
+	for _, item := range list.Items {
+		info := kubeNodeInfo{ready: "NotReady", kubeletVersion: item.Status.NodeInfo.KubeletVersion}
+		for _, c := range item.Status.Conditions {
+			if c.Type == "Ready" && c.Status == "True" {
+				info.ready = "Ready"
+			}
+		}
+		infos[item.Metadata.Name] = info
+	}
+	return infos
+}
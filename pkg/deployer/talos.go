@@ -0,0 +1,155 @@
+package deployer
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// talosconfigPath 返回集群 talosconfig 路径
+func (d *Deployer) talosconfigPath() string {
+	return filepath.Join(d.config.ConfigDir(), "talosconfig")
+}
+
+// talosctl 构建使用集群 talosconfig 的 talosctl 命令
+func (d *Deployer) talosctl(args ...string) *exec.Cmd {
+	return d.command("talosctl", append([]string{"--talosconfig", d.talosconfigPath()}, args...)...)
+}
+
+// controlPlaneEndpoints 返回所有控制平面 IP
+func (d *Deployer) controlPlaneEndpoints() []string {
+	var endpoints []string
+	for _, node := range d.config.Nodes.ControlPlanes {
+		endpoints = append(endpoints, node.IPAddress)
+	}
+	return endpoints
+}
+
+// configureTalosEndpoints 将控制平面端点写入集群 talosconfig
+func (d *Deployer) configureTalosEndpoints() error {
+	cmd := d.talosctl(append([]string{"config", "endpoint"}, d.controlPlaneEndpoints()...)...)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("配置端点失败: %w", err)
+	}
+
+	cmd = d.talosctl("config", "node", d.bootstrapNode().IPAddress)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("配置节点失败: %w", err)
+	}
+	return nil
+}
+
+// bootstrapNode 返回执行 etcd 引导的控制平面节点，引导和从快照恢复都在该节点上进行
+func (d *Deployer) bootstrapNode() config.NodeSpec {
+	return d.config.Nodes.ControlPlanes[0]
+}
+
+// ApplyNodeConfig 将节点专属配置应用到节点
+// insecure 为 true 时用于维护模式下的首次应用，否则通过 talosconfig 认证重新应用
+func (d *Deployer) ApplyNodeConfig(node config.NodeSpec, insecure bool) error {
+	args := []string{"apply-config",
+		"--nodes", node.IPAddress,
+		"--file", d.nodeConfigPath(node),
+		"--timeout", "5m",
+	}
+
+	var cmd *exec.Cmd
+	if insecure {
+		cmd = d.command("talosctl", append(args, "--insecure")...)
+	} else {
+		cmd = d.talosctl(append(args, "--endpoints", node.IPAddress)...)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// talosOutput 执行 talosctl 命令并返回输出，超时后终止
+func (d *Deployer) talosOutput(timeout time.Duration, args ...string) ([]byte, error) {
+	cmd := d.talosctl(args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	timer := time.AfterFunc(timeout, func() { cmd.Process.Kill() })
+	defer timer.Stop()
+
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// isNodeConfigured 判断节点是否已退出维护模式
+// 维护模式的节点不接受 talosconfig 认证的请求
+func (d *Deployer) isNodeConfigured(node config.NodeSpec) bool {
+	_, err := d.talosOutput(15*time.Second,
+		"--nodes", node.IPAddress, "--endpoints", node.IPAddress, "version")
+	return err == nil
+}
+
+// isEtcdBootstrapped 判断 etcd 是否已经引导
+func (d *Deployer) isEtcdBootstrapped(ip string) bool {
+	out, err := d.talosOutput(15*time.Second,
+		"--nodes", ip, "--endpoints", ip, "etcd", "members")
+	return err == nil && len(bytes.TrimSpace(out)) > 0
+}
+
+// resetNode 通过 Talos 优雅重置节点：离开集群、清除系统分区并关机
+func (d *Deployer) resetNode(node config.NodeSpec) error {
+	_, err := d.talosOutput(5*time.Minute,
+		"--nodes", node.IPAddress, "--endpoints", node.IPAddress,
+		"reset", "--graceful=true", "--reboot=false")
+	return err
+}
+
+// NodeTalosVersion 返回节点上运行的 Talos 版本，如 v1.6.0
+func (d *Deployer) NodeTalosVersion(node config.NodeSpec) (string, error) {
+	out, err := d.talosOutput(15*time.Second,
+		"--nodes", node.IPAddress, "--endpoints", node.IPAddress, "version")
+	if err != nil {
+		return "", err
+	}
+	tag := parseServerTag(string(out))
+	if tag == "" {
+		return "", fmt.Errorf("无法解析 talosctl version 输出")
+	}
+	return tag, nil
+}
+
+// parseServerTag 从 talosctl version 输出的 Server 段中提取 Tag
+func parseServerTag(out string) string {
+	inServer := false
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "Server:" {
+			inServer = true
+			continue
+		}
+		if inServer {
+			if tag, ok := strings.CutPrefix(line, "Tag:"); ok {
+				return strings.TrimSpace(tag)
+			}
+		}
+	}
+	return ""
+}
+
+// firstReachableControlPlane 返回第一个 Talos API 可访问的控制平面节点
+func (d *Deployer) firstReachableControlPlane() (config.NodeSpec, error) {
+	for _, node := range d.config.Nodes.ControlPlanes {
+		if d.isNodeConfigured(node) {
+			return node, nil
+		}
+	}
+	return config.NodeSpec{}, fmt.Errorf("没有可访问的控制平面节点")
+}
@@ -0,0 +1,355 @@
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// TalosUpgradeOptions Talos 升级选项
+type TalosUpgradeOptions struct {
+	Version  string   // 目标版本，留空使用配置中的 talos_version
+	Nodes    []string // 只升级指定节点，留空升级所有节点
+	DryRun   bool     // 只输出升级计划，不执行
+	Stage    bool     // 暂存升级，节点重启时再安装（用于文件被占用导致升级失败的情况）
+	Snapshot bool     // 升级前为所有节点创建快照
+}
+
+// nodeUpgrade 单个节点的升级计划
+type nodeUpgrade struct {
+	node     config.NodeSpec
+	current  string
+	preserve bool // 传递 --preserve，保留 EPHEMERAL 分区
+}
+
+// preserveDefaultVersion Talos 从该版本起升级时默认保留 EPHEMERAL 分区
+var preserveDefaultVersion = config.Version{Major: 1, Minor: 8}
+
+// needsPreserve 判断从 current 升级时是否需要显式传递 --preserve
+// 更早的版本升级时会清空 EPHEMERAL 分区，控制平面的 etcd 数据随之丢失，单控制平面集群将无法恢复
+func needsPreserve(current config.Version) bool {
+	return current.CompareMinor(preserveDefaultVersion) < 0
+}
+
+// controlPlanesFirst 按控制平面在前、工作节点在后排序，同角色内保持原顺序
+func controlPlanesFirst(nodes []config.NodeSpec) []config.NodeSpec {
+	sorted := append([]config.NodeSpec(nil), nodes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Role == "controlplane" && sorted[j].Role != "controlplane"
+	})
+	return sorted
+}
+
+// UpgradeTalos 逐个节点滚动升级 Talos，控制平面在前
+// 每个节点升级后等待其运行新版本且 etcd 健康，任一节点失败立即停止
+func (d *Deployer) UpgradeTalos(opts TalosUpgradeOptions) error {
+	target := opts.Version
+	if target == "" {
+		target = d.config.TalosVersion
+	}
+	targetVersion, err := config.ParseVersion(target)
+	if err != nil {
+		return fmt.Errorf("目标 Talos 版本无效: %w", err)
+	}
+	target = "v" + targetVersion.String()
+
+	// 升级前确认目标版本支持当前 Kubernetes 版本
+	if d.config.KubernetesVersion != "" {
+		known, err := config.CheckKubernetesSupport(target, d.config.KubernetesVersion)
+		if err != nil {
+			return err
+		}
+		if !known {
+			fmt.Printf("⚠️  Talos %s 不在内置支持矩阵中，无法校验 Kubernetes %s 的兼容性\n",
+				target, d.config.KubernetesVersion)
+		}
+	}
+
+	nodes, err := d.selectNodes(opts.Nodes)
+	if err != nil {
+		return err
+	}
+	nodes = controlPlanesFirst(nodes)
+
+	image := d.config.InstallImage(target)
+	fmt.Printf("⬆️  升级 Talos 到 %s\n", target)
+	fmt.Printf("  安装镜像: %s\n", image)
+	if opts.Stage {
+		fmt.Println("  暂存模式: 升级在节点重启时安装")
+	}
+	fmt.Println()
+
+	// 收集各节点当前版本，确定需要升级的节点
+	var pending []nodeUpgrade
+	for _, node := range nodes {
+		current, err := d.NodeTalosVersion(node)
+		if err != nil {
+			return fmt.Errorf("获取节点 %s 的 Talos 版本失败: %w", node.Name, err)
+		}
+		currentVersion, err := config.ParseVersion(current)
+		if err != nil {
+			return fmt.Errorf("节点 %s 的 Talos 版本无效: %w", node.Name, err)
+		}
+
+		switch c := currentVersion.Compare(targetVersion); {
+		case c == 0:
+			fmt.Printf("  ✓ %s: 已是 %s\n", node.Name, current)
+		case c > 0:
+			return fmt.Errorf("节点 %s 运行的 Talos %s 高于目标版本 %s，不支持降级", node.Name, current, target)
+		default:
+			fmt.Printf("  • %s: %s → %s\n", node.Name, current, target)
+			if needsPreserve(currentVersion) {
+				fmt.Printf("    使用 --preserve 保留 EPHEMERAL 分区（etcd 数据）\n")
+			}
+			if targetVersion.Minor-currentVersion.Minor > 1 {
+				fmt.Printf("    ⚠️  跨越多个次版本，建议逐个次版本升级\n")
+			}
+			pending = append(pending, nodeUpgrade{node: node, current: current, preserve: needsPreserve(currentVersion)})
+		}
+	}
+	fmt.Println()
+
+	if opts.DryRun {
+		fmt.Printf("共 %d 个节点需要升级（--dry-run，未执行）\n", len(pending))
+		return nil
+	}
+
+	if len(pending) > 0 {
+		// 集群健康时才开始升级
+		if err := d.waitEtcdHealthy(nodeLogger{}); err != nil {
+			return fmt.Errorf("升级前检查失败: %w", err)
+		}
+		if opts.Snapshot {
+			if err := d.preUpgradeSnapshot(); err != nil {
+				return err
+			}
+		}
+
+		for i, u := range pending {
+			fmt.Printf("  [%d/%d] 升级节点: %s (%s → %s)\n", i+1, len(pending), u.node.Name, u.current, target)
+			if err := d.upgradeNode(u, image, target, opts.Stage); err != nil {
+				return fmt.Errorf("升级节点 %s 失败，已停止升级: %w", u.node.Name, err)
+			}
+		}
+	}
+
+	return d.finishTalosUpgrade(target)
+}
+
+// preUpgradeSnapshot 升级前为所有节点创建快照，失败时中止升级
+func (d *Deployer) preUpgradeSnapshot() error {
+	if err := d.CreateSnapshot(preUpgradeSnapshotName(), false); err != nil {
+		return fmt.Errorf("升级前创建快照失败: %w", err)
+	}
+	fmt.Println()
+	return nil
+}
+
+// upgradeNode 升级单个节点并等待其恢复健康
+func (d *Deployer) upgradeNode(u nodeUpgrade, image, target string, stage bool) error {
+	node := u.node
+	args := []string{"upgrade",
+		"--nodes", node.IPAddress,
+		"--endpoints", node.IPAddress,
+		"--image", image,
+	}
+	if u.preserve {
+		args = append(args, "--preserve")
+	}
+	if stage {
+		args = append(args, "--stage")
+	}
+	if out, err := d.talosctl(args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	log := nodeLogger{}
+	if err := waitFor(log, "节点运行 Talos "+target, d.config.Deploy.Timeouts.UpgradeTimeout(), pollInterval, func() (bool, error) {
+		current, err := d.NodeTalosVersion(node)
+		if err != nil {
+			return false, err
+		}
+		return current == target, nil
+	}); err != nil {
+		return err
+	}
+
+	if node.Role == "controlplane" {
+		if err := d.waitEtcdHealthy(log); err != nil {
+			return err
+		}
+	}
+
+	return d.recordNodeTalosVersion(node, target)
+}
+
+// finishTalosUpgrade 所有节点都运行目标版本后，更新配置文件、部署状态和节点配置
+func (d *Deployer) finishTalosUpgrade(target string) error {
+	var remaining []string
+	for _, node := range d.config.AllNodes() {
+		current, err := d.NodeTalosVersion(node)
+		if err != nil || current != target {
+			remaining = append(remaining, node.Name)
+		}
+	}
+	if len(remaining) > 0 {
+		fmt.Printf("✓ 所选节点已升级，%s 尚未运行 %s，全部升级后才会更新配置中的 talos_version\n",
+			strings.Join(remaining, ", "), target)
+		return nil
+	}
+
+	if d.config.TalosVersion != target {
+		d.config.TalosVersion = target
+		if err := d.config.Save(); err != nil {
+			return fmt.Errorf("更新配置文件失败: %w", err)
+		}
+		fmt.Printf("✓ 配置文件中的 talos_version 已更新为 %s\n", target)
+	}
+	if err := d.recordTalosVersion(target); err != nil {
+		return err
+	}
+
+	// 重新生成节点配置，使安装镜像与新版本一致
+	if err := d.RenderNodeConfigs(); err != nil {
+		fmt.Printf("⚠️  重新生成节点配置失败: %v\n", err)
+	}
+
+	fmt.Printf("✓ 集群已升级到 Talos %s\n", target)
+	return nil
+}
+
+// KubernetesUpgradeOptions Kubernetes 升级选项
+type KubernetesUpgradeOptions struct {
+	DryRun   bool // 只执行 talosctl upgrade-k8s --dry-run 预检，不升级
+	Snapshot bool // 升级前为所有节点创建快照
+}
+
+// UpgradeKubernetes 将运行中集群的 Kubernetes 升级到配置中的 kubernetes_version
+// 通过第一个可访问的控制平面执行 talosctl upgrade-k8s，前后打印各组件版本
+func (d *Deployer) UpgradeKubernetes(opts KubernetesUpgradeOptions) error {
+	if d.config.KubernetesVersion == "" {
+		return fmt.Errorf("配置中未设置 kubernetes_version")
+	}
+	target := d.config.KubernetesVersionFull()
+	targetVersion, err := config.ParseVersion(d.config.KubernetesVersion)
+	if err != nil {
+		return fmt.Errorf("kubernetes_version 无效: %w", err)
+	}
+
+	known, err := config.CheckKubernetesSupport(d.config.TalosVersion, target)
+	if err != nil {
+		return err
+	}
+	if !known {
+		fmt.Printf("⚠️  Talos %s 不在内置支持矩阵中，无法校验 Kubernetes %s 的兼容性\n",
+			d.config.TalosVersion, target)
+	}
+
+	before, err := d.ComponentVersions()
+	if err != nil {
+		return err
+	}
+	fmt.Println("当前组件版本:")
+	printComponentVersions(before)
+	fmt.Println()
+
+	var outdated int
+	for _, v := range before {
+		if !versionMatches(d.config.KubernetesVersion, v.Version) {
+			outdated++
+		}
+	}
+	if outdated == 0 {
+		fmt.Printf("✓ 所有组件已是 Kubernetes %s，无需升级\n", d.config.KubernetesVersion)
+		return nil
+	}
+
+	current, err := d.ServerVersion()
+	if err != nil {
+		return err
+	}
+	currentVersion, err := config.ParseVersion(current)
+	if err != nil {
+		return fmt.Errorf("无法解析 API Server 版本 %s: %w", current, err)
+	}
+	// 配置只写 major.minor 且与 API Server 同一次版本时，按 API Server 的补丁版本补齐落后的组件
+	if !targetVersion.HasPatch && targetVersion.CompareMinor(currentVersion) == 0 {
+		targetVersion = currentVersion
+		target = currentVersion.String()
+	}
+	if targetVersion.Compare(currentVersion) < 0 {
+		return fmt.Errorf("API Server 运行的 %s 高于目标版本 %s，不支持降级", current, target)
+	}
+	if targetVersion.Major == currentVersion.Major && targetVersion.Minor-currentVersion.Minor > 1 {
+		return fmt.Errorf("Kubernetes 只能逐个次版本升级: 当前 %s，目标 %s，请先将 kubernetes_version 设置为 \"%d.%d\"",
+			current, target, currentVersion.Major, currentVersion.Minor+1)
+	}
+
+	cp, err := d.firstReachableControlPlane()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("⬆️  升级 Kubernetes: %s → v%s（通过控制平面 %s）\n", current, target, cp.Name)
+	args := []string{"upgrade-k8s",
+		"--nodes", cp.IPAddress,
+		"--endpoints", cp.IPAddress,
+		"--to", target,
+	}
+	if opts.DryRun {
+		args = append(args, "--dry-run")
+	} else if opts.Snapshot {
+		if err := d.preUpgradeSnapshot(); err != nil {
+			return err
+		}
+	}
+	cmd := d.talosctl(args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if opts.DryRun {
+			return fmt.Errorf("升级预检失败: %w", err)
+		}
+		return fmt.Errorf("Kubernetes 升级失败: %w", err)
+	}
+
+	if opts.DryRun {
+		fmt.Println()
+		fmt.Printf("✓ 预检通过，%d 个组件需要升级（--dry-run，未执行）\n", outdated)
+		return nil
+	}
+
+	if err := d.waitKubernetesAPI(nodeLogger{}); err != nil {
+		return err
+	}
+
+	after, err := d.ComponentVersions()
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+	fmt.Println("升级后组件版本:")
+	printComponentVersions(after)
+
+	var mismatches []string
+	for _, v := range after {
+		if !versionMatches(d.config.KubernetesVersion, v.Version) {
+			mismatches = append(mismatches, fmt.Sprintf("%s (%s): %s", v.Component, v.Node, v.Version))
+		}
+	}
+	if len(mismatches) > 0 {
+		fmt.Println()
+		fmt.Println("⚠️  以下组件仍未运行目标版本:")
+		for _, m := range mismatches {
+			fmt.Printf("  - %s\n", m)
+		}
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Printf("✓ Kubernetes 已升级到 v%s\n", target)
+	return nil
+}
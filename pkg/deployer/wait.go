@@ -0,0 +1,172 @@
+package deployer
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+const (
+	// talosAPIPort Talos API 端口，维护模式下同样监听
+	talosAPIPort = 50000
+
+	// pollInterval 就绪检查间隔
+	pollInterval = 3 * time.Second
+	// progressInterval 等待期间输出进度的间隔
+	progressInterval = 30 * time.Second
+)
+
+// waitFor 每隔 interval 调用一次 ready，直到返回 true 或超过 timeout
+// 超时错误中附带最后一次检查的错误，便于定位原因
+func waitFor(log nodeLogger, what string, timeout, interval time.Duration, ready func() (bool, error)) error {
+	log.Printf("    等待%s...\n", what)
+
+	start := time.Now()
+	lastReport := start
+	for {
+		ok, err := ready()
+		if ok {
+			log.Printf("    ✓ %s (%s)\n", what, time.Since(start).Round(time.Second))
+			return nil
+		}
+
+		if time.Since(start) >= timeout {
+			if err != nil {
+				return fmt.Errorf("等待%s超时 (%s): %w", what, timeout, err)
+			}
+			return fmt.Errorf("等待%s超时 (%s)", what, timeout)
+		}
+		if time.Since(lastReport) >= progressInterval {
+			log.Printf("    仍在等待%s (已等待 %s)\n", what, time.Since(start).Round(time.Second))
+			lastReport = time.Now()
+		}
+		time.Sleep(interval)
+	}
+}
+
+// waitVMRunning 等待虚拟机在 Proxmox 中进入运行状态
+func (d *Deployer) waitVMRunning(node config.NodeSpec, log nodeLogger) error {
+	return waitFor(log, "虚拟机运行", d.config.Deploy.Timeouts.VMRunningTimeout(), time.Second, func() (bool, error) {
+		status, err := d.backend.VMStatus(node.VMID)
+		if err != nil {
+			return false, err
+		}
+		return status.Status == "running", nil
+	})
+}
+
+// waitVMStopped 等待虚拟机在 Proxmox 中停止
+func (d *Deployer) waitVMStopped(node config.NodeSpec, timeout time.Duration, log nodeLogger) error {
+	return waitFor(log, "虚拟机关机", timeout, pollInterval, func() (bool, error) {
+		status, err := d.backend.VMStatus(node.VMID)
+		if err != nil {
+			return false, err
+		}
+		return status.Status == "stopped", nil
+	})
+}
+
+// waitTalosAPI 等待 Talos API 端口响应，新节点此时处于维护模式
+func (d *Deployer) waitTalosAPI(node config.NodeSpec, log nodeLogger) error {
+	addr := net.JoinHostPort(node.IPAddress, fmt.Sprint(talosAPIPort))
+	return waitFor(log, "节点 Talos API 响应 ("+addr+")", d.config.Deploy.Timeouts.TalosAPITimeout(), pollInterval, func() (bool, error) {
+		conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+		if err != nil {
+			return false, err
+		}
+		conn.Close()
+		return true, nil
+	})
+}
+
+// waitConfigured 等待节点应用配置后安装、重启并退出维护模式
+func (d *Deployer) waitConfigured(node config.NodeSpec, log nodeLogger) error {
+	return waitFor(log, "节点退出维护模式", d.config.Deploy.Timeouts.ConfiguredTimeout(), pollInterval, func() (bool, error) {
+		return d.isNodeConfigured(node), nil
+	})
+}
+
+// waitEtcdHealthy 等待所有控制平面节点上的 etcd 成员健康
+func (d *Deployer) waitEtcdHealthy(log nodeLogger) error {
+	nodes := strings.Join(d.controlPlaneEndpoints(), ",")
+	firstCP := d.config.Nodes.ControlPlanes[0].IPAddress
+	return waitFor(log, "控制平面 etcd 健康", d.config.Deploy.Timeouts.EtcdTimeout(), pollInterval, func() (bool, error) {
+		if _, err := d.talosOutput(15*time.Second,
+			"--nodes", nodes, "--endpoints", firstCP, "etcd", "status"); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+}
+
+// waitEtcdQuorum 等待 etcd 恢复 quorum
+// 成员列表是线性一致读，只有多数成员在线时才能返回，任一控制平面返回即可
+func (d *Deployer) waitEtcdQuorum(log nodeLogger) error {
+	return waitFor(log, "etcd 恢复 quorum", d.config.Deploy.Timeouts.EtcdTimeout(), pollInterval, func() (bool, error) {
+		var lastErr error
+		for _, cp := range d.config.Nodes.ControlPlanes {
+			if _, err := d.etcdMembers(cp); err != nil {
+				lastErr = err
+				continue
+			}
+			return true, nil
+		}
+		return false, lastErr
+	})
+}
+
+// waitEtcdJoined 等待控制平面作为正式成员（非 learner）加入 etcd
+func (d *Deployer) waitEtcdJoined(node, peer config.NodeSpec, log nodeLogger) error {
+	return waitFor(log, "节点加入 etcd", d.config.Deploy.Timeouts.EtcdTimeout(), pollInterval, func() (bool, error) {
+		members, err := d.etcdMembers(peer)
+		if err != nil {
+			return false, err
+		}
+		for _, m := range members {
+			if m.Hostname == node.Name && !m.Learner {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+// waitNodeReady 等待节点加入集群并在 Kubernetes 中 Ready
+func (d *Deployer) waitNodeReady(node config.NodeSpec, log nodeLogger) error {
+	return waitFor(log, "节点在 Kubernetes 中 Ready", d.config.Deploy.Timeouts.NodeReadyTimeout(), pollInterval, func() (bool, error) {
+		out, err := d.kubectl("get", "node", node.Name,
+			"-o", `jsonpath={.status.conditions[?(@.type=="Ready")].status}`).CombinedOutput()
+		if err != nil {
+			return false, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+		}
+		return strings.TrimSpace(string(out)) == "True", nil
+	})
+}
+
+// waitNodeRebooted 等待 Kubernetes 中的节点报告新的 boot ID 并 Ready
+// 节点状态更新前 Ready 可能仍是重启前的值，因此同时比较 boot ID
+func (d *Deployer) waitNodeRebooted(node config.NodeSpec, bootID string, log nodeLogger) error {
+	return waitFor(log, "节点在 Kubernetes 中重新 Ready", d.config.Deploy.Timeouts.NodeReadyTimeout(), pollInterval, func() (bool, error) {
+		out, err := d.kubectl("get", "node", node.Name,
+			"-o", `jsonpath={.status.nodeInfo.bootID} {.status.conditions[?(@.type=="Ready")].status}`).CombinedOutput()
+		if err != nil {
+			return false, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+		}
+		return strings.TrimSpace(string(out)) == bootID+" True", nil
+	})
+}
+
+// waitKubernetesAPI 等待 Kubernetes API 在 6443 端口就绪
+func (d *Deployer) waitKubernetesAPI(log nodeLogger) error {
+	what := "集群 Kubernetes API 就绪 (" + d.config.ControlPlaneEndpoint() + ")"
+	return waitFor(log, what, d.config.Deploy.Timeouts.KubernetesAPITimeout(), pollInterval, func() (bool, error) {
+		out, err := d.kubectl("get", "--raw", "/readyz", "--request-timeout", "10s").CombinedOutput()
+		if err != nil {
+			return false, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+		}
+		return true, nil
+	})
+}
@@ -0,0 +1,430 @@
+package proxmox
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// DefaultPort Proxmox VE API 默认端口
+const DefaultPort = 8006
+
+// DefaultTaskTimeout 等待异步任务完成的默认超时，足够克隆或导入较大的磁盘
+const DefaultTaskTimeout = 30 * time.Minute
+
+// DefaultRequestTimeout 单个 API 请求的默认超时，不适用于上传镜像
+const DefaultRequestTimeout = 5 * time.Minute
+
+// Client Proxmox VE /api2/json REST 客户端
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	cfg        config.ProxmoxConfig
+
+	// mu 保护票据和节点名，客户端可被多个 goroutine 并发使用
+	mu sync.Mutex
+
+	// 票据认证（password）
+	ticket string
+	csrf   string
+
+	node string
+
+	// requestTimeout 单个 API 请求的超时
+	requestTimeout time.Duration
+
+	// taskTimeout 等待异步任务完成的超时，taskPoll 查询任务状态的间隔
+	taskTimeout time.Duration
+	taskPoll    time.Duration
+}
+
+// APIError Proxmox API 返回的错误
+type APIError struct {
+	StatusCode int
+	Message    string
+	Errors     map[string]string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("Proxmox API 错误 (%d): %s", e.StatusCode, e.Message)
+	if len(e.Errors) > 0 {
+		var details []string
+		for k, v := range e.Errors {
+			details = append(details, fmt.Sprintf("%s: %s", k, strings.TrimSpace(v)))
+		}
+		msg += " [" + strings.Join(details, "; ") + "]"
+	}
+	return msg
+}
+
+// IsNotFound 判断错误是否表示资源不存在
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusNotFound ||
+		strings.Contains(apiErr.Message, "does not exist")
+}
+
+// NewClient 根据 Proxmox 配置创建 API 客户端
+func NewClient(cfg config.ProxmoxConfig) (*Client, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("未配置 Proxmox 主机")
+	}
+
+	baseURL := cfg.Host
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		port := cfg.Port
+		if port == 0 {
+			port = DefaultPort
+		}
+		baseURL = fmt.Sprintf("https://%s:%d", baseURL, port)
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/") + "/api2/json"
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: cfg.SkipTLSVerify}
+
+	// 不设置整体超时：上传镜像的请求体可能需要很长时间，API 请求的超时由 apiRequest 按请求设置
+	return &Client{
+		baseURL:        baseURL,
+		httpClient:     &http.Client{Transport: transport},
+		cfg:            cfg,
+		node:           cfg.Node,
+		requestTimeout: DefaultRequestTimeout,
+		taskTimeout:    DefaultTaskTimeout,
+		taskPoll:       time.Second,
+	}, nil
+}
+
+// apiRequest 创建带 requestTimeout 超时的 API 请求，读取完响应后调用 cancel
+func (c *Client) apiRequest(method, u string, body io.Reader) (*http.Request, context.CancelFunc, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.requestTimeout)
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	return req, cancel, nil
+}
+
+// SetTaskTimeout 设置等待异步任务完成的超时
+func (c *Client) SetTaskTimeout(timeout time.Duration) {
+	c.taskTimeout = timeout
+}
+
+// useToken 判断是否使用 API Token 认证
+func (c *Client) useToken() bool {
+	switch c.cfg.AuthMethod {
+	case "api_token":
+		return true
+	case "password":
+		return false
+	default:
+		// 兼容旧配置：优先使用 API Token
+		return c.cfg.APITokenID != "" && c.cfg.APIToken != ""
+	}
+}
+
+// tokenHeader 构建 API Token 认证头
+func (c *Client) tokenHeader() string {
+	tokenID := c.cfg.APITokenID
+	if !strings.Contains(tokenID, "!") {
+		tokenID = c.cfg.User + "!" + tokenID
+	}
+	return fmt.Sprintf("PVEAPIToken=%s=%s", tokenID, c.cfg.APIToken)
+}
+
+// Login 使用用户名密码获取票据和 CSRF Token
+func (c *Client) Login() error {
+	if c.useToken() {
+		return nil
+	}
+
+	form := url.Values{}
+	form.Set("username", c.cfg.User)
+	form.Set("password", c.cfg.Password)
+
+	var result struct {
+		Ticket string `json:"ticket"`
+		CSRF   string `json:"CSRFPreventionToken"`
+	}
+	req, cancel, err := c.apiRequest(http.MethodPost, c.baseURL+"/access/ticket", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := c.send(req, &result); err != nil {
+		return fmt.Errorf("Proxmox 登录失败: %w", err)
+	}
+
+	c.mu.Lock()
+	c.ticket = result.Ticket
+	c.csrf = result.CSRF
+	c.mu.Unlock()
+	return nil
+}
+
+// authorize 为请求附加认证信息
+func (c *Client) authorize(req *http.Request) error {
+	if c.useToken() {
+		req.Header.Set("Authorization", c.tokenHeader())
+		return nil
+	}
+
+	c.mu.Lock()
+	loggedIn := c.ticket != ""
+	c.mu.Unlock()
+	if !loggedIn {
+		if err := c.Login(); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	req.AddCookie(&http.Cookie{Name: "PVEAuthCookie", Value: c.ticket})
+	if req.Method != http.MethodGet {
+		req.Header.Set("CSRFPreventionToken", c.csrf)
+	}
+	return nil
+}
+
+// Get 发送 GET 请求，结果解析到 out
+func (c *Client) Get(path string, query url.Values, out interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, cancel, err := c.apiRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	return c.do(req, out)
+}
+
+// Post 发送 POST 请求
+func (c *Client) Post(path string, params url.Values, out interface{}) error {
+	return c.doForm(http.MethodPost, path, params, out)
+}
+
+// Put 发送 PUT 请求
+func (c *Client) Put(path string, params url.Values, out interface{}) error {
+	return c.doForm(http.MethodPut, path, params, out)
+}
+
+// Delete 发送 DELETE 请求
+func (c *Client) Delete(path string, params url.Values, out interface{}) error {
+	u := c.baseURL + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	req, cancel, err := c.apiRequest(http.MethodDelete, u, nil)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	return c.do(req, out)
+}
+
+func (c *Client) doForm(method, path string, params url.Values, out interface{}) error {
+	req, cancel, err := c.apiRequest(method, c.baseURL+path, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.do(req, out)
+}
+
+func (c *Client) do(req *http.Request, out interface{}) error {
+	if err := c.authorize(req); err != nil {
+		return err
+	}
+	return c.send(req, out)
+}
+
+// send 发送请求并解析 {"data": ...} 响应
+func (c *Client) send(req *http.Request, out interface{}) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 %s 失败: %w", req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	var envelope struct {
+		Data   json.RawMessage   `json:"data"`
+		Errors map[string]string `json:"errors"`
+	}
+	if len(body) > 0 {
+		// 错误响应可能不是 JSON，忽略解析错误
+		_ = json.Unmarshal(body, &envelope)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Proxmox 将错误原因放在状态行中
+		msg := strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprintf("%d", resp.StatusCode)))
+		return &APIError{StatusCode: resp.StatusCode, Message: msg, Errors: envelope.Errors}
+	}
+
+	if out == nil || len(envelope.Data) == 0 || string(envelope.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	return nil
+}
+
+// Node 返回操作所在的 Proxmox 节点名
+// 未配置时自动探测，仅支持单节点环境
+func (c *Client) Node() (string, error) {
+	c.mu.Lock()
+	node := c.node
+	c.mu.Unlock()
+	if node != "" {
+		return node, nil
+	}
+
+	var nodes []struct {
+		Node   string `json:"node"`
+		Status string `json:"status"`
+	}
+	if err := c.Get("/nodes", nil, &nodes); err != nil {
+		return "", fmt.Errorf("获取 Proxmox 节点列表失败: %w", err)
+	}
+	switch len(nodes) {
+	case 0:
+		return "", fmt.Errorf("未找到 Proxmox 节点")
+	case 1:
+		c.mu.Lock()
+		c.node = nodes[0].Node
+		c.mu.Unlock()
+		return nodes[0].Node, nil
+	default:
+		return "", fmt.Errorf("检测到 %d 个 Proxmox 节点，请在配置中设置 proxmox.node", len(nodes))
+	}
+}
+
+// Version 返回 Proxmox VE 版本
+func (c *Client) Version() (string, error) {
+	var v struct {
+		Version string `json:"version"`
+		Release string `json:"release"`
+	}
+	if err := c.Get("/version", nil, &v); err != nil {
+		return "", err
+	}
+	return v.Version, nil
+}
+
+// WaitTask 等待异步任务（UPID）完成，超过 taskTimeout 仍未结束时返回错误
+// 超时只停止等待，任务本身仍在 Proxmox 上继续运行
+func (c *Client) WaitTask(upid string) error {
+	if upid == "" {
+		return nil
+	}
+	node, err := c.Node()
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/nodes/%s/tasks/%s/status", node, url.PathEscape(upid))
+	deadline := time.Now().Add(c.taskTimeout)
+	for {
+		var status struct {
+			Status     string `json:"status"`
+			ExitStatus string `json:"exitstatus"`
+		}
+		if err := c.Get(path, nil, &status); err != nil {
+			return fmt.Errorf("查询任务状态失败: %w", err)
+		}
+		if status.Status == "stopped" {
+			if status.ExitStatus != "OK" {
+				return fmt.Errorf("任务 %s 失败: %s", upid, status.ExitStatus)
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待任务 %s 超时（%s），任务状态: %s", upid, c.taskTimeout, status.Status)
+		}
+		time.Sleep(c.taskPoll)
+	}
+}
+
+// Upload 上传本地文件到存储，content 为 iso、vztmpl 或 import
+func (c *Client) Upload(storage, content, localPath string) error {
+	node, err := c.Node()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer f.Close()
+
+	// 以流方式构建 multipart 请求体，避免将镜像全部读入内存
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		err := func() error {
+			if err := mw.WriteField("content", content); err != nil {
+				return err
+			}
+			part, err := mw.CreateFormFile("filename", filepath.Base(localPath))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(part, f); err != nil {
+				return err
+			}
+			return mw.Close()
+		}()
+		pw.CloseWithError(err)
+	}()
+
+	// 上传不设置超时，大镜像经较慢的链路上传可能超过 requestTimeout
+	path := fmt.Sprintf("/nodes/%s/storage/%s/upload", node, storage)
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+path, pr)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var upid string
+	if err := c.do(req, &upid); err != nil {
+		return fmt.Errorf("上传 %s 失败: %w", filepath.Base(localPath), err)
+	}
+	return c.WaitTask(upid)
+}
+
+// encodeBool 将布尔值编码为 Proxmox 参数
+func encodeBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,350 @@
+package proxmox
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"talos-proxmox-deployer/pkg/config"
+)
+
+// stubServer 模拟 Proxmox API 的本地 HTTP 服务，按 "方法 路径" 注册处理函数
+type stubServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	handlers map[string]http.HandlerFunc
+	requests []*http.Request
+}
+
+func newStubServer(t *testing.T) *stubServer {
+	s := &stubServer{handlers: make(map[string]http.HandlerFunc)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		key := r.Method + " " + strings.TrimPrefix(r.URL.Path, "/api2/json")
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		h, ok := s.handlers[key]
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, "no such handler: "+key, http.StatusNotImplemented)
+			return
+		}
+		h(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stubServer) handle(key string, h http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[key] = h
+}
+
+// count 返回匹配 "方法 路径" 的请求数
+func (s *stubServer) count(key string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, r := range s.requests {
+		if r.Method+" "+strings.TrimPrefix(r.URL.Path, "/api2/json") == key {
+			n++
+		}
+	}
+	return n
+}
+
+// writeData 按 Proxmox 的 {"data": ...} 格式写响应
+func writeData(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func newTestClient(t *testing.T, s *stubServer, cfg config.ProxmoxConfig) *Client {
+	cfg.Host = s.URL
+	if cfg.Node == "" {
+		cfg.Node = "pve"
+	}
+	c, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	c.taskPoll = 10 * time.Millisecond
+	return c
+}
+
+func TestTokenAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		tokenID string
+		want    string
+	}{
+		{"只有 token 名", "deploy", "PVEAPIToken=root@pam!deploy=secret"},
+		{"完整 token ID", "admin@pve!ci", "PVEAPIToken=admin@pve!ci=secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStubServer(t)
+			s.handle("GET /version", func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != tt.want {
+					http.Error(w, "bad token "+got, http.StatusUnauthorized)
+					return
+				}
+				writeData(w, map[string]string{"version": "8.1.4"})
+			})
+			s.handle("POST /nodes/pve/qemu/100/status/start", func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != tt.want {
+					http.Error(w, "bad token "+got, http.StatusUnauthorized)
+					return
+				}
+				if r.Header.Get("CSRFPreventionToken") != "" {
+					t.Errorf("token 认证不应发送 CSRF token")
+				}
+				writeData(w, nil)
+			})
+
+			c := newTestClient(t, s, config.ProxmoxConfig{
+				User: "root@pam", AuthMethod: "api_token", APITokenID: tt.tokenID, APIToken: "secret",
+			})
+			v, err := c.Version()
+			if err != nil {
+				t.Fatalf("Version: %v", err)
+			}
+			if v != "8.1.4" {
+				t.Errorf("Version = %q，期望 8.1.4", v)
+			}
+			if err := c.Post("/nodes/pve/qemu/100/status/start", nil, nil); err != nil {
+				t.Fatalf("Post: %v", err)
+			}
+			if n := s.count("POST /access/ticket"); n != 0 {
+				t.Errorf("token 认证不应登录，登录了 %d 次", n)
+			}
+		})
+	}
+}
+
+func TestTicketAuth(t *testing.T) {
+	s := newStubServer(t)
+	s.handle("POST /access/ticket", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostForm.Get("username") != "root@pam" || r.PostForm.Get("password") != "hunter2" {
+			http.Error(w, "authentication failure", http.StatusUnauthorized)
+			return
+		}
+		writeData(w, map[string]string{"ticket": "PVE:ticket", "CSRFPreventionToken": "csrf-token"})
+	})
+	checkCookie := func(w http.ResponseWriter, r *http.Request) bool {
+		cookie, err := r.Cookie("PVEAuthCookie")
+		if err != nil || cookie.Value != "PVE:ticket" {
+			http.Error(w, "no ticket", http.StatusUnauthorized)
+			return false
+		}
+		return true
+	}
+	s.handle("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		if !checkCookie(w, r) {
+			return
+		}
+		if r.Header.Get("CSRFPreventionToken") != "" {
+			t.Errorf("GET 请求不应发送 CSRF token")
+		}
+		writeData(w, map[string]string{"version": "8.1.4"})
+	})
+	s.handle("POST /nodes/pve/qemu/100/config", func(w http.ResponseWriter, r *http.Request) {
+		if !checkCookie(w, r) {
+			return
+		}
+		if got := r.Header.Get("CSRFPreventionToken"); got != "csrf-token" {
+			http.Error(w, "bad csrf "+got, http.StatusUnauthorized)
+			return
+		}
+		if r.PostForm.Get("memory") != "2048" {
+			t.Errorf("memory = %q，期望 2048", r.PostForm.Get("memory"))
+		}
+		writeData(w, nil)
+	})
+
+	c := newTestClient(t, s, config.ProxmoxConfig{User: "root@pam", AuthMethod: "password", Password: "hunter2"})
+	if _, err := c.Version(); err != nil {
+		t.Fatalf("Version: %v", err)
+	}
+	if err := c.SetVMConfig(100, map[string]string{"memory": "2048"}); err != nil {
+		t.Fatalf("SetVMConfig: %v", err)
+	}
+	if n := s.count("POST /access/ticket"); n != 1 {
+		t.Errorf("登录 %d 次，期望票据复用只登录 1 次", n)
+	}
+}
+
+func TestTicketAuthFailure(t *testing.T) {
+	s := newStubServer(t)
+	s.handle("POST /access/ticket", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "authentication failure", http.StatusUnauthorized)
+	})
+
+	c := newTestClient(t, s, config.ProxmoxConfig{User: "root@pam", AuthMethod: "password", Password: "wrong"})
+	_, err := c.Version()
+	if err == nil || !strings.Contains(err.Error(), "Proxmox 登录失败") {
+		t.Fatalf("Version 错误 = %v，期望登录失败", err)
+	}
+	if n := s.count("GET /version"); n != 0 {
+		t.Errorf("登录失败后仍发送了 %d 次请求", n)
+	}
+}
+
+// taskHandler 按顺序返回任务状态，最后一个状态一直重复
+func taskHandler(statuses ...map[string]string) http.HandlerFunc {
+	var mu sync.Mutex
+	i := 0
+	return func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		status := statuses[i]
+		if i < len(statuses)-1 {
+			i++
+		}
+		mu.Unlock()
+		writeData(w, status)
+	}
+}
+
+func TestWaitTask(t *testing.T) {
+	const upid = "UPID:pve:0000ABCD:00112233:65A1B2C3:qmclone:100:root@pam:"
+	running := map[string]string{"status": "running"}
+	taskPath := "GET /nodes/pve/tasks/" + upid + "/status"
+
+	t.Run("轮询直到完成", func(t *testing.T) {
+		s := newStubServer(t)
+		s.handle(taskPath, taskHandler(running, running, map[string]string{"status": "stopped", "exitstatus": "OK"}))
+
+		c := newTestClient(t, s, config.ProxmoxConfig{User: "root@pam", APITokenID: "deploy", APIToken: "secret"})
+		if err := c.WaitTask(upid); err != nil {
+			t.Fatalf("WaitTask: %v", err)
+		}
+		if n := s.count(taskPath); n != 3 {
+			t.Errorf("查询任务状态 %d 次，期望 3 次", n)
+		}
+	})
+
+	t.Run("任务失败", func(t *testing.T) {
+		s := newStubServer(t)
+		s.handle(taskPath, taskHandler(map[string]string{"status": "stopped", "exitstatus": "clone failed: no space left"}))
+
+		c := newTestClient(t, s, config.ProxmoxConfig{User: "root@pam", APITokenID: "deploy", APIToken: "secret"})
+		err := c.WaitTask(upid)
+		if err == nil || !strings.Contains(err.Error(), "no space left") {
+			t.Fatalf("WaitTask 错误 = %v，期望包含任务退出状态", err)
+		}
+	})
+
+	t.Run("超时", func(t *testing.T) {
+		s := newStubServer(t)
+		s.handle(taskPath, taskHandler(running))
+
+		c := newTestClient(t, s, config.ProxmoxConfig{User: "root@pam", APITokenID: "deploy", APIToken: "secret"})
+		c.SetTaskTimeout(50 * time.Millisecond)
+		done := make(chan error, 1)
+		go func() { done <- c.WaitTask(upid) }()
+		select {
+		case err := <-done:
+			if err == nil || !strings.Contains(err.Error(), "超时") {
+				t.Fatalf("WaitTask 错误 = %v，期望超时", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("WaitTask 未在超时后返回")
+		}
+	})
+
+	t.Run("异步操作等待 UPID", func(t *testing.T) {
+		s := newStubServer(t)
+		s.handle("POST /nodes/pve/qemu/100/clone", func(w http.ResponseWriter, r *http.Request) {
+			if r.PostForm.Get("newid") != "101" || r.PostForm.Get("name") != "talos-cp-1" {
+				t.Errorf("clone 参数 = %v", r.PostForm)
+			}
+			writeData(w, upid)
+		})
+		s.handle(taskPath, taskHandler(running, map[string]string{"status": "stopped", "exitstatus": "OK"}))
+
+		c := newTestClient(t, s, config.ProxmoxConfig{User: "root@pam", APITokenID: "deploy", APIToken: "secret"})
+		if err := c.CloneVM(100, 101, "talos-cp-1", false); err != nil {
+			t.Fatalf("CloneVM: %v", err)
+		}
+		if n := s.count(taskPath); n != 2 {
+			t.Errorf("查询任务状态 %d 次，期望 2 次", n)
+		}
+	})
+}
+
+func TestIsNotFound(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"404", &APIError{StatusCode: http.StatusNotFound}, true},
+		{"配置不存在", &APIError{StatusCode: http.StatusInternalServerError, Message: "Configuration file 'nodes/pve/qemu-server/100.conf' does not exist"}, true},
+		{"被包装的错误", fmt.Errorf("读取虚拟机状态失败: %w", &APIError{StatusCode: http.StatusNotFound}), true},
+		{"其他 API 错误", &APIError{StatusCode: http.StatusForbidden, Message: "Permission check failed"}, false},
+		{"非 API 错误", fmt.Errorf("connection refused"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsNotFound(tt.err); got != tt.want {
+				t.Errorf("IsNotFound(%v) = %v，期望 %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	const upid = "UPID:pve:0000ABCD:00112233:65A1B2C3:imgcopy::root@pam:"
+	s := newStubServer(t)
+	s.handle("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+		writeData(w, map[string]string{"version": "8.1.4"})
+	})
+	// 模拟较慢的上传：读取请求体所需时间超过单个 API 请求的超时
+	s.handle("POST /nodes/pve/storage/local/upload", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f, _, err := r.FormFile("filename")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(f)
+		if string(data) != "qcow2" {
+			t.Errorf("上传内容 = %q", data)
+		}
+		writeData(w, upid)
+	})
+	s.handle("GET /nodes/pve/tasks/"+upid+"/status", taskHandler(map[string]string{"status": "stopped", "exitstatus": "OK"}))
+
+	c := newTestClient(t, s, config.ProxmoxConfig{User: "root@pam", APITokenID: "deploy", APIToken: "secret"})
+	c.requestTimeout = 50 * time.Millisecond
+
+	if _, err := c.Version(); err == nil {
+		t.Error("Version 未在请求超时后返回错误")
+	}
+
+	image := filepath.Join(t.TempDir(), "talos.qcow2")
+	if err := os.WriteFile(image, []byte("qcow2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Upload("local", "import", image); err != nil {
+		t.Fatalf("Upload 受请求超时限制: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package proxmox
+
+import (
+	"net/url"
+	"sort"
+)
+
+// Snapshot 虚拟机快照
+type Snapshot struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	SnapTime    int64  `json:"snaptime"` // Unix 时间戳
+	Parent      string `json:"parent"`
+	VMState     int    `json:"vmstate"` // 是否包含内存状态
+}
+
+// ListSnapshots 列出虚拟机快照（不含表示当前状态的 "current"），按创建时间排序
+func (c *Client) ListSnapshots(vmID int) ([]Snapshot, error) {
+	path, err := c.vmPath(vmID, "/snapshot")
+	if err != nil {
+		return nil, err
+	}
+
+	var all []Snapshot
+	if err := c.Get(path, nil, &all); err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]Snapshot, 0, len(all))
+	for _, s := range all {
+		if s.Name != "current" {
+			snapshots = append(snapshots, s)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].SnapTime < snapshots[j].SnapTime })
+	return snapshots, nil
+}
+
+// CreateSnapshot 创建快照，vmstate 为 true 时同时保存内存状态
+func (c *Client) CreateSnapshot(vmID int, name, description string, vmstate bool) error {
+	path, err := c.vmPath(vmID, "/snapshot")
+	if err != nil {
+		return err
+	}
+	params := url.Values{}
+	params.Set("snapname", name)
+	if description != "" {
+		params.Set("description", description)
+	}
+	params.Set("vmstate", encodeBool(vmstate))
+	return c.postTask(path, params)
+}
+
+// RollbackSnapshot 将虚拟机回滚到快照
+func (c *Client) RollbackSnapshot(vmID int, name string) error {
+	path, err := c.vmPath(vmID, "/snapshot/"+url.PathEscape(name)+"/rollback")
+	if err != nil {
+		return err
+	}
+	return c.postTask(path, nil)
+}
+
+// DeleteSnapshot 删除快照
+func (c *Client) DeleteSnapshot(vmID int, name string) error {
+	path, err := c.vmPath(vmID, "/snapshot/"+url.PathEscape(name))
+	if err != nil {
+		return err
+	}
+
+	var upid string
+	if err := c.Delete(path, nil, &upid); err != nil {
+		return err
+	}
+	return c.WaitTask(upid)
+}
@@ -0,0 +1,215 @@
+package proxmox
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// VMStatus 虚拟机当前状态
+type VMStatus struct {
+	VMID     int     `json:"vmid"`
+	Name     string  `json:"name"`
+	Status   string  `json:"status"` // running / stopped
+	CPUs     int     `json:"cpus"`
+	CPU      float64 `json:"cpu"` // CPU 使用率（0-1）
+	MaxMem   int64   `json:"maxmem"`
+	Mem      int64   `json:"mem"`
+	MaxDisk  int64   `json:"maxdisk"`
+	Uptime   int64   `json:"uptime"`
+	Template int     `json:"template"`
+	Tags     string  `json:"tags"`
+}
+
+// vmPath 返回虚拟机 API 路径
+func (c *Client) vmPath(vmID int, suffix string) (string, error) {
+	node, err := c.Node()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("/nodes/%s/qemu/%d%s", node, vmID, suffix), nil
+}
+
+// postTask 发送 POST 请求并等待返回的任务完成
+func (c *Client) postTask(path string, params url.Values) error {
+	var upid string
+	if err := c.Post(path, params, &upid); err != nil {
+		return err
+	}
+	return c.WaitTask(upid)
+}
+
+// VMParams 将选项映射转换为 API 参数（键名与 qm 命令行选项一致）
+func VMParams(opts map[string]string) url.Values {
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	params := url.Values{}
+	for _, k := range keys {
+		params.Set(k, opts[k])
+	}
+	return params
+}
+
+// GetVMStatus 获取虚拟机状态
+func (c *Client) GetVMStatus(vmID int) (*VMStatus, error) {
+	path, err := c.vmPath(vmID, "/status/current")
+	if err != nil {
+		return nil, err
+	}
+	var status VMStatus
+	if err := c.Get(path, nil, &status); err != nil {
+		return nil, err
+	}
+	status.VMID = vmID
+	return &status, nil
+}
+
+// ListVMs 列出节点上的所有虚拟机（含模板），按 VM ID 排序
+func (c *Client) ListVMs() ([]VMStatus, error) {
+	node, err := c.Node()
+	if err != nil {
+		return nil, err
+	}
+	var vms []VMStatus
+	if err := c.Get(fmt.Sprintf("/nodes/%s/qemu", node), nil, &vms); err != nil {
+		return nil, err
+	}
+	sort.Slice(vms, func(i, j int) bool { return vms[i].VMID < vms[j].VMID })
+	return vms, nil
+}
+
+// GetVMConfig 获取虚拟机配置，所有值均转换为字符串
+func (c *Client) GetVMConfig(vmID int) (map[string]string, error) {
+	path, err := c.vmPath(vmID, "/config")
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	if err := c.Get(path, nil, &raw); err != nil {
+		return nil, err
+	}
+
+	cfg := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch val := v.(type) {
+		case string:
+			cfg[k] = val
+		case float64:
+			cfg[k] = strconv.FormatFloat(val, 'f', -1, 64)
+		default:
+			cfg[k] = fmt.Sprintf("%v", val)
+		}
+	}
+	return cfg, nil
+}
+
+// CreateVM 创建虚拟机
+func (c *Client) CreateVM(vmID int, opts map[string]string) error {
+	node, err := c.Node()
+	if err != nil {
+		return err
+	}
+	params := VMParams(opts)
+	params.Set("vmid", strconv.Itoa(vmID))
+	return c.postTask(fmt.Sprintf("/nodes/%s/qemu", node), params)
+}
+
+// CloneVM 从模板克隆虚拟机
+func (c *Client) CloneVM(srcID, dstID int, name string, full bool) error {
+	path, err := c.vmPath(srcID, "/clone")
+	if err != nil {
+		return err
+	}
+	params := url.Values{}
+	params.Set("newid", strconv.Itoa(dstID))
+	params.Set("name", name)
+	params.Set("full", encodeBool(full))
+	return c.postTask(path, params)
+}
+
+// SetVMConfig 修改虚拟机配置
+func (c *Client) SetVMConfig(vmID int, opts map[string]string) error {
+	path, err := c.vmPath(vmID, "/config")
+	if err != nil {
+		return err
+	}
+	return c.postTask(path, VMParams(opts))
+}
+
+// ResizeDisk 调整虚拟机磁盘大小，size 形如 "50G" 或 "+10G"
+func (c *Client) ResizeDisk(vmID int, disk, size string) error {
+	path, err := c.vmPath(vmID, "/resize")
+	if err != nil {
+		return err
+	}
+	params := url.Values{}
+	params.Set("disk", disk)
+	params.Set("size", size)
+	var upid string
+	if err := c.Put(path, params, &upid); err != nil {
+		return err
+	}
+	return c.WaitTask(upid)
+}
+
+// StartVM 启动虚拟机
+func (c *Client) StartVM(vmID int) error {
+	path, err := c.vmPath(vmID, "/status/start")
+	if err != nil {
+		return err
+	}
+	return c.postTask(path, nil)
+}
+
+// StopVM 立即停止虚拟机（相当于拔电源）
+func (c *Client) StopVM(vmID int) error {
+	path, err := c.vmPath(vmID, "/status/stop")
+	if err != nil {
+		return err
+	}
+	return c.postTask(path, nil)
+}
+
+// ShutdownVM 通过 ACPI 优雅关机，超时后强制停止
+func (c *Client) ShutdownVM(vmID int, timeout time.Duration) error {
+	path, err := c.vmPath(vmID, "/status/shutdown")
+	if err != nil {
+		return err
+	}
+	params := url.Values{}
+	params.Set("timeout", strconv.Itoa(int(timeout.Seconds())))
+	params.Set("forceStop", "1")
+	return c.postTask(path, params)
+}
+
+// DeleteVM 删除虚拟机及其磁盘
+func (c *Client) DeleteVM(vmID int) error {
+	path, err := c.vmPath(vmID, "")
+	if err != nil {
+		return err
+	}
+	params := url.Values{}
+	params.Set("purge", "1")
+	params.Set("destroy-unreferenced-disks", "1")
+
+	var upid string
+	if err := c.Delete(path, params, &upid); err != nil {
+		return err
+	}
+	return c.WaitTask(upid)
+}
+
+// ConvertToTemplate 将虚拟机转换为模板
+func (c *Client) ConvertToTemplate(vmID int) error {
+	path, err := c.vmPath(vmID, "/template")
+	if err != nil {
+		return err
+	}
+	return c.postTask(path, nil)
+}
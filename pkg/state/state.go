@@ -0,0 +1,200 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName 状态文件名，保存在集群配置目录中
+const FileName = "state.yaml"
+
+// State 记录部署器实际创建的资源
+type State struct {
+	ClusterName  string                `yaml:"cluster_name" json:"cluster_name"`
+	TemplateVMID int                   `yaml:"template_vm_id,omitempty" json:"template_vm_id,omitempty"`
+	TalosVersion string                `yaml:"talos_version,omitempty" json:"talos_version,omitempty"`
+	Nodes        map[string]*NodeState `yaml:"nodes,omitempty" json:"nodes,omitempty"`                 // 按节点名
+	ConfigHashes map[string]string     `yaml:"config_hashes,omitempty" json:"config_hashes,omitempty"` // 配置文件 → sha256
+	Bootstrapped bool                  `yaml:"bootstrapped" json:"bootstrapped"`
+
+	CompletedSteps []string `yaml:"completed_steps,omitempty" json:"completed_steps,omitempty"` // 已完成的部署步骤
+	Failure        *Failure `yaml:"failure,omitempty" json:"failure,omitempty"`                 // 最近一次失败
+
+	UpdatedAt time.Time `yaml:"updated_at" json:"updated_at"`
+
+	// mu 使用指针，序列化时 yaml 通过反射复制结构体不会读到锁本身
+	mu   *sync.Mutex
+	path string
+}
+
+// NodeState 单个节点的实际状态
+type NodeState struct {
+	VMID         int    `yaml:"vm_id" json:"vm_id"`
+	Role         string `yaml:"role" json:"role"`
+	IPAddress    string `yaml:"ip_address" json:"ip_address"`
+	MAC          string `yaml:"mac,omitempty" json:"mac,omitempty"`
+	PVENode      string `yaml:"pve_node,omitempty" json:"pve_node,omitempty"`
+	TalosVersion string `yaml:"talos_version,omitempty" json:"talos_version,omitempty"`
+	ConfigHash   string `yaml:"config_hash,omitempty" json:"config_hash,omitempty"`
+	InputHash    string `yaml:"input_hash,omitempty" json:"input_hash,omitempty"` // 生成节点配置时全部输入的哈希，用于 plan 判断是否需要重新生成
+	Created      bool   `yaml:"created" json:"created"`                           // 虚拟机已创建并启动
+	Configured   bool   `yaml:"configured" json:"configured"`                     // 已应用 Talos 配置
+}
+
+// Failure 部署失败的位置，用于 deploy --resume
+type Failure struct {
+	Step  string    `yaml:"step" json:"step"`
+	Node  string    `yaml:"node,omitempty" json:"node,omitempty"`
+	Error string    `yaml:"error" json:"error"`
+	Time  time.Time `yaml:"time" json:"time"`
+}
+
+// Path 返回集群配置目录下的状态文件路径
+func Path(configDir string) string {
+	return filepath.Join(configDir, FileName)
+}
+
+// Load 读取状态文件，不存在时返回空状态
+func Load(path string) (*State, error) {
+	s := &State{path: path, mu: &sync.Mutex{}}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取状态文件失败: %w", err)
+	}
+	if err := yaml.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("解析状态文件失败: %w", err)
+	}
+	return s, nil
+}
+
+// Exists 判断状态文件是否已存在
+func (s *State) Exists() bool {
+	_, err := os.Stat(s.path)
+	return err == nil
+}
+
+// Update 在锁内修改状态并立即保存
+func (s *State) Update(fn func(s *State)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn(s)
+	return s.save()
+}
+
+// save 原子写入状态文件，调用方需持有锁
+func (s *State) save() error {
+	s.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("序列化状态失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("创建状态目录失败: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入状态文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("写入状态文件失败: %w", err)
+	}
+	return nil
+}
+
+// Node 返回节点状态，不存在时创建
+func (s *State) Node(name string) *NodeState {
+	if s.Nodes == nil {
+		s.Nodes = make(map[string]*NodeState)
+	}
+	n, ok := s.Nodes[name]
+	if !ok {
+		n = &NodeState{}
+		s.Nodes[name] = n
+	}
+	return n
+}
+
+// NodeNames 返回已记录的节点名（控制平面在前，按名称排序）
+func (s *State) NodeNames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.Nodes))
+	for name := range s.Nodes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := s.Nodes[names[i]], s.Nodes[names[j]]
+		if (a.Role == "controlplane") != (b.Role == "controlplane") {
+			return a.Role == "controlplane"
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// Lookup 返回已记录的节点状态
+func (s *State) Lookup(name string) (NodeState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.Nodes[name]
+	if !ok {
+		return NodeState{}, false
+	}
+	return *n, true
+}
+
+// StepCompleted 判断部署步骤是否已完成
+func (s *State) StepCompleted(step string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.CompletedSteps {
+		if c == step {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkStep 将步骤标记为已完成，调用方需通过 Update 持有锁
+func (s *State) MarkStep(step string) {
+	for _, c := range s.CompletedSteps {
+		if c == step {
+			return
+		}
+	}
+	s.CompletedSteps = append(s.CompletedSteps, step)
+}
+
+// HashFile 计算文件的 sha256
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}